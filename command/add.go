@@ -1,45 +1,179 @@
 package command
 
 import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
 	"github.com/tracerun/clientgo"
+	"github.com/tracerun/tracerun/service"
 	"github.com/urfave/cli"
 )
 
+// maxBatchBytes of the actions of a batch, under service.MaxRequestBytes
+// with room for the rest of the request
+const maxBatchBytes = service.MaxRequestBytes - 1024
+
 // NewAddCMD create a add command. Used to add actions.
 func NewAddCMD() cli.Command {
 	return cli.Command{
 		Name:   "add",
 		Usage:  "add an action to db",
 		Action: addAction,
-		Flags: []cli.Flag{
-			cli.StringFlag{
+		Flags: append([]cli.Flag{
+			cli.StringSliceFlag{
 				Name:  "target, t",
-				Usage: "Target for the action",
+				Usage: "Target for the action, repeat it to add several actions at once",
+			},
+			cli.BoolFlag{
+				Name:  "ack",
+				Usage: "Wait until the actions are written, exit with an error if not",
 			},
-			cli.StringFlag{
-				Name:  "addr",
-				Usage: "Address that need to connect",
-				Value: "127.0.0.1",
+			cli.BoolFlag{
+				Name:  "stdin",
+				Usage: "Read buffered actions from stdin, one \"target\" or \"unixtime<TAB>target\" a line",
 			},
-		},
+		}, connFlags...),
 	}
 }
 
 func addAction(c *cli.Context) error {
-	target := c.String("target")
-	if target == "" {
+	targets := c.StringSlice("target")
+	var actions []*service.TimedAction
+	for _, target := range targets {
+		actions = append(actions, &service.TimedAction{Target: target})
+	}
+	if c.Bool("stdin") {
+		read, err := readActions(os.Stdin)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		actions = append(actions, read...)
+	}
+	if len(actions) == 0 {
 		return cli.NewExitError("missing target, -h help", 2)
 	}
 
-	addr := c.String("addr")
-	p := uint16(c.GlobalUint("p"))
-	client, exist, err := clientgo.NewSendClient(p, addr)
+	ack := c.Bool("ack")
+	if ack && len(actions) == 1 {
+		return sendAck(c, actions[0])
+	}
+	// clientgo only speaks plain TCP, without authentication
+	if len(targets) == 1 && len(actions) == 1 && len(c.String("socket")) == 0 && !useTLS(c) && len(clientToken(c)) == 0 {
+		p := uint16(c.GlobalUint("p"))
+		client, exist, err := clientgo.NewSendClient(p, c.String("addr"))
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		if !exist {
+			return cli.NewExitError("service unavailable", 2)
+		}
+
+		return client.SendAction(targets[0])
+	}
+
+	return sendBatch(c, actions, ack)
+}
+
+// sendAck to send an action and wait until it is written.
+func sendAck(c *cli.Context, action *service.TimedAction) error {
+	client, err := connect(c)
 	if err != nil {
 		return cli.NewExitError(err, 2)
 	}
-	if !exist {
-		return cli.NewExitError("service unavailable", 2)
+	defer client.Close()
+
+	var ack service.Ack
+	if err := client.call(ackRoute, action, &ack); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
+
+// sendBatch to send the actions in as few round trips as the queue capacity
+// of the service and the request size allow.
+func sendBatch(c *cli.Context, actions []*service.TimedAction, ack bool) error {
+	client, err := connect(c)
+	if err != nil {
+		return cli.NewExitError(err, 2)
 	}
+	defer client.Close()
 
-	return client.SendAction(target)
+	var stats service.Stats
+	if err := client.call(statsRoute, nil, &stats); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	var rejected uint32
+	for _, batch := range splitBatch(actions, int(stats.Capacity), maxBatchBytes) {
+		var result service.BatchResult
+		if err := client.call(batchRoute, &service.ActionBatch{Actions: batch, Ack: ack}, &result); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+
+		for i, item := range result.Items {
+			if !item.Accepted && i < len(batch) {
+				fmt.Fprintf(os.Stderr, "rejected %s: %s\n", batch[i].Target, item.Error)
+			}
+		}
+		rejected += result.Rejected
+	}
+	if rejected > 0 {
+		return cli.NewExitError(fmt.Sprintf("%d of %d actions rejected", rejected, len(actions)), 1)
+	}
+	return nil
+}
+
+// splitBatch to split actions in batches of at most size actions and about
+// maxBytes once encoded. An action larger than maxBytes is a batch alone.
+func splitBatch(actions []*service.TimedAction, size, maxBytes int) [][]*service.TimedAction {
+	if size <= 0 {
+		size = len(actions)
+	}
+
+	var batches [][]*service.TimedAction
+	var batch []*service.TimedAction
+	bytes := 0
+	for _, a := range actions {
+		// the field key and length of the action
+		n := proto.Size(a) + binary.MaxVarintLen32 + 1
+		if len(batch) > 0 && (len(batch) == size || bytes+n > maxBytes) {
+			batches = append(batches, batch)
+			batch, bytes = nil, 0
+		}
+		batch = append(batch, a)
+		bytes += n
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// readActions to read one action a line, "target" or "unixtime<TAB>target".
+func readActions(r io.Reader) ([]*service.TimedAction, error) {
+	var actions []*service.TimedAction
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) == 0 {
+			continue
+		}
+
+		a := &service.TimedAction{Target: line}
+		if idx := strings.Index(line, "\t"); idx > 0 {
+			if ts, err := strconv.ParseUint(line[:idx], 10, 32); err == nil {
+				a.Ts = uint32(ts)
+				a.Target = line[idx+1:]
+			}
+		}
+		actions = append(actions, a)
+	}
+	return actions, scanner.Err()
 }
@@ -0,0 +1,49 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/tracerun/tracerun/service"
+	"github.com/urfave/cli"
+)
+
+// NewAdminCMD to control the service, with an admin token or by the Unix
+// socket as the service user.
+func NewAdminCMD() cli.Command {
+	return cli.Command{
+		Name:      "admin",
+		Usage:     "control the service: shutdown, restart-listener, flush or reload",
+		ArgsUsage: "<operation>",
+		Action:    adminAction,
+		Flags:     connFlags,
+	}
+}
+
+func adminAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.NewExitError("expect one operation, -h help", 2)
+	}
+
+	client, err := connect(c)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	defer client.Close()
+
+	var result service.AdminResult
+	if err := client.call(adminRoute, &service.AdminRequest{Op: c.Args().First()}, &result); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	switch result.Op {
+	case service.AdminShutdown:
+		fmt.Println("service stopping")
+	case service.AdminRestartListener:
+		fmt.Println("listener restarted")
+	case service.AdminFlush:
+		fmt.Printf("%d actions flushed\n", result.Flushed)
+	case service.AdminReload:
+		printReload(result.Reload)
+	}
+	return nil
+}
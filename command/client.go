@@ -0,0 +1,269 @@
+package command
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/tracerun/tracerun/service"
+	"github.com/urfave/cli"
+)
+
+const (
+	dialTimeout = 3
+	callTimeout = 30
+)
+
+// routes of the service
+const (
+	metaRoute    = uint8(2)
+	statsRoute   = uint8(3)
+	helloRoute   = uint8(4)
+	rulesRoute   = uint8(5)
+	expiryRoute  = uint8(6)
+	reloadRoute  = uint8(7)
+	authRoute    = uint8(8)
+	adminRoute   = uint8(9)
+	actionsRoute = uint8(11)
+	batchRoute   = uint8(13)
+	ackRoute     = uint8(14)
+	targetsRoute = uint8(20)
+	slotsRoute   = uint8(21)
+	reportRoute  = uint8(23)
+	treeRoute    = uint8(24)
+	// errorRoute the route of service.ErrorMessage replies
+	errorRoute = uint8(255)
+)
+
+// errUnavailable when the service can't be reached
+var errUnavailable = errors.New("service unavailable")
+
+// conn to call the routes not covered by clientgo yet.
+type conn struct {
+	c       net.Conn
+	version uint8
+	lastID  uint32
+}
+
+// connect to the service by the "socket" flag if set, or by the "addr" flag
+// and the port.
+func connect(c *cli.Context) (*conn, error) {
+	var client *conn
+	var err error
+	if socket := c.String("socket"); len(socket) != 0 {
+		client, err = dialSocket(socket)
+	} else if useTLS(c) {
+		var cfg *tls.Config
+		if cfg, err = clientTLS(c); err != nil {
+			return nil, err
+		}
+		client, err = dialTLS(uint16(c.GlobalUint("p")), c.String("addr"), cfg)
+	} else {
+		client, err = dial(uint16(c.GlobalUint("p")), c.String("addr"))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if t := clientToken(c); len(t) != 0 {
+		host, _ := os.Hostname()
+		if err := client.call(authRoute, &service.AuthRequest{Token: t, Host: host}, &service.AuthResult{}); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// clientToken to get the token by the "token" flag or TRACERUN_TOKEN, or of
+// the token file. It's empty if there is none to read.
+func clientToken(c *cli.Context) string {
+	if t := c.GlobalString("token"); len(t) != 0 {
+		return t
+	}
+	file := c.GlobalString("token-file")
+	if len(file) == 0 {
+		file = c.GlobalString("db") + ".token"
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func dial(port uint16, addr string) (*conn, error) {
+	return open("tcp", net.JoinHostPort(addr, fmt.Sprint(port)))
+}
+
+// dialSocket to connect by a Unix socket.
+func dialSocket(path string) (*conn, error) {
+	return open("unix", path)
+}
+
+// dialTLS to connect by TCP with TLS.
+func dialTLS(port uint16, addr string, cfg *tls.Config) (*conn, error) {
+	d := &net.Dialer{Timeout: dialTimeout * time.Second}
+	c, err := tls.DialWithDialer(d, "tcp", net.JoinHostPort(addr, fmt.Sprint(port)), cfg)
+	if err != nil {
+		if _, ok := err.(net.Error); ok {
+			return nil, errUnavailable
+		}
+		return nil, err
+	}
+	return start(c)
+}
+
+// useTLS to check whether the "tls" or "ca" flag asks for TLS.
+func useTLS(c *cli.Context) bool {
+	return c.Bool("tls") || len(c.String("ca")) != 0
+}
+
+// connFlags of every command connecting to the service, see connect.
+var connFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "addr",
+		Usage: "Address that need to connect",
+		Value: "127.0.0.1",
+	},
+	cli.StringFlag{
+		Name:  "socket",
+		Usage: "Unix socket that need to connect, instead of addr",
+	},
+	cli.BoolFlag{
+		Name:  "tls",
+		Usage: "Connect with TLS, verified by the system roots or --ca",
+	},
+	cli.StringFlag{
+		Name:  "ca",
+		Usage: "CA file to verify the service certificate, implies --tls",
+	},
+	cli.StringFlag{
+		Name:  "cert",
+		Usage: "Client certificate file, for a service verifying clients",
+	},
+	cli.StringFlag{
+		Name:  "key",
+		Usage: "Key file of the client certificate",
+	},
+}
+
+// clientTLS to get the TLS config of the "ca", "cert" and "key" flags.
+func clientTLS(c *cli.Context) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if ca := c.String("ca"); len(ca) != 0 {
+		pool, err := service.LoadCertPool(ca)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+
+	cert, key := c.String("cert"), c.String("key")
+	if len(cert) != 0 || len(key) != 0 {
+		if len(cert) == 0 || len(key) == 0 {
+			return nil, service.ErrTLSConfig
+		}
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+	return cfg, nil
+}
+
+func open(network, address string) (*conn, error) {
+	c, err := net.DialTimeout(network, address, dialTimeout*time.Second)
+	if err != nil {
+		return nil, errUnavailable
+	}
+	return start(c)
+}
+
+// start to say hello on a connection opened.
+func start(c net.Conn) (*conn, error) {
+	client := &conn{c: c, version: service.Version1}
+	if err := client.hello(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// hello to agree the highest frame version. The Hello is sent to the meta
+// route, which every daemon replies: an older one ignores it and the
+// connection keeps service.Version1. A daemon asking for a token refuses meta
+// first, the hello route it has is called instead.
+func (c *conn) hello() error {
+	h := &service.Hello{Version: uint32(service.MaxVersion)}
+
+	var meta service.Meta
+	err := c.call(metaRoute, h, &meta)
+	if err != nil && err.Error() == service.ErrUnauthorized.Error() {
+		var reply service.Hello
+		if err := c.call(helloRoute, h, &reply); err != nil {
+			return err
+		}
+		c.version = uint8(reply.Version)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if meta.FrameVersion != 0 {
+		c.version = uint8(meta.FrameVersion)
+	}
+	return nil
+}
+
+func (c *conn) Close() error {
+	return c.c.Close()
+}
+
+// call a route with req and read the reply into resp. A nil req sends no
+// data, an ErrorMessage reply is returned as error.
+func (c *conn) call(route uint8, req, resp proto.Message) error {
+	var buf []byte
+	if req != nil {
+		var err error
+		if buf, err = proto.Marshal(req); err != nil {
+			return err
+		}
+	}
+	if len(buf) > service.MaxRequestBytes || len(buf) > service.MaxDataBytes(c.version) {
+		return service.ErrFrameTooLarge
+	}
+
+	c.lastID++
+	c.c.SetDeadline(time.Now().Add(callTimeout * time.Second))
+	headerBuf := service.GenerateFrameHeader(c.version, uint32(len(buf)), route, c.lastID)
+	if _, err := c.c.Write(append(headerBuf, buf...)); err != nil {
+		return err
+	}
+
+	data, replyRoute, id, err := service.ReadFrame(c.c, c.version)
+	if err != nil {
+		return err
+	}
+	if c.version >= service.Version2 && id != c.lastID {
+		return fmt.Errorf("reply of request %d, expect %d", id, c.lastID)
+	}
+	switch replyRoute {
+	case route:
+		return proto.Unmarshal(data, resp)
+	case errorRoute:
+		var errMsg service.ErrorMessage
+		if err := proto.Unmarshal(data, &errMsg); err != nil {
+			return err
+		}
+		return errors.New(errMsg.Message)
+	default:
+		return fmt.Errorf("unexpected reply route %d", replyRoute)
+	}
+}
@@ -0,0 +1,83 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tracerun/tracerun/service"
+	"github.com/urfave/cli"
+)
+
+// NewExpiryCMD to show and change the checks of expired actions.
+func NewExpiryCMD() cli.Command {
+	return cli.Command{
+		Name:   "expiry",
+		Usage:  "show or change how often expired actions are closed, their timeout is tdb's own",
+		Action: expiryAction,
+		Flags: append([]cli.Flag{
+			cli.BoolFlag{
+				Name:  "json, j",
+				Usage: "Show result with JSON.",
+			},
+			cli.DurationFlag{
+				Name:  "interval, i",
+				Usage: "Set the interval between two checks, at least 1s.",
+			},
+		}, connFlags...),
+	}
+}
+
+// Expiry the checks of expired actions
+type Expiry struct {
+	Interval     uint32 `json:"interval"`
+	LastCheck    uint32 `json:"last_check"`
+	LastExpired  uint32 `json:"last_expired"`
+	LastDuration uint32 `json:"last_duration"`
+	Checks       uint64 `json:"checks"`
+}
+
+func expiryAction(c *cli.Context) error {
+	client, err := connect(c)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	defer client.Close()
+
+	req := &service.ExpiryRequest{
+		Interval: uint32(c.Duration("interval") / time.Second),
+	}
+	if c.IsSet("interval") && req.Interval == 0 {
+		return cli.NewExitError(service.ErrCheckInterval, 1)
+	}
+	var e service.Expiry
+	if err := client.call(expiryRoute, req, &e); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	if c.Bool("json") {
+		b, _ := json.Marshal(Expiry{
+			Interval:     e.Interval,
+			LastCheck:    e.LastCheck,
+			LastExpired:  e.LastExpired,
+			LastDuration: e.LastDuration,
+			Checks:       e.Checks,
+		})
+		fmt.Println(string(b))
+	} else {
+		printExpiry(&e)
+	}
+	return nil
+}
+
+func printExpiry(e *service.Expiry) {
+	fmt.Printf("interval:\t%s\n", time.Duration(e.Interval)*time.Second)
+	if e.LastCheck == 0 {
+		fmt.Println("no check yet")
+		return
+	}
+	fmt.Printf("last check:\t%s\n", time.Unix(int64(e.LastCheck), 0).Format("2006-01-02 15:04:05"))
+	fmt.Printf("expired:\t%d\n", e.LastExpired)
+	fmt.Printf("took:\t\t%s\n", time.Duration(e.LastDuration)*time.Millisecond)
+	fmt.Printf("checks:\t\t%d\n", e.Checks)
+}
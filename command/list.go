@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/tracerun/clientgo"
+	"github.com/tracerun/tracerun/service"
 	"github.com/urfave/cli"
 )
 
@@ -15,12 +15,7 @@ func NewListCMD() cli.Command {
 		Name:   "list",
 		Usage:  "list content in db",
 		Action: listAction,
-		Flags: []cli.Flag{
-			cli.StringFlag{
-				Name:  "addr",
-				Usage: "Address that need to connect",
-				Value: "127.0.0.1",
-			},
+		Flags: append([]cli.Flag{
 			cli.BoolFlag{
 				Name:  "json, j",
 				Usage: "Show result with JSON.",
@@ -37,6 +32,31 @@ func NewListCMD() cli.Command {
 				Name:  "slots",
 				Usage: "List all slots of a target.",
 			},
+			cli.BoolFlag{
+				Name:  "all-slots",
+				Usage: "List the slots of all targets matched by --match or --regex.",
+			},
+			cli.StringFlag{
+				Name:  "match, m",
+				Usage: "Only list the targets matching the glob, like \"project/**/*.go\".",
+			},
+			cli.StringFlag{
+				Name:  "regex, r",
+				Usage: "Only list the targets matching the regular expression.",
+			},
+			cli.BoolFlag{
+				Name:  "tree",
+				Usage: "List targets as a tree with the time spent on each level.",
+			},
+			cli.StringFlag{
+				Name:  "prefix",
+				Usage: "Root of the tree, all targets if not set.",
+			},
+			cli.UintFlag{
+				Name:  "depth",
+				Usage: "Levels of the tree below the root, 0 for all.",
+				Value: uint(0),
+			},
 			cli.UintFlag{
 				Name:  "start, s",
 				Usage: "The start unixtime to query for slots, 0 for 1970-1-1 00:00:00.",
@@ -47,7 +67,12 @@ func NewListCMD() cli.Command {
 				Usage: "The end unixtime to query for slots, 0 for 2106-2-7 06:28:15.",
 				Value: uint(0),
 			},
-		},
+			cli.UintFlag{
+				Name:  "page-size",
+				Usage: "Slots to get in one request, 0 to get all in one request.",
+				Value: uint(1000),
+			},
+		}, connFlags...),
 	}
 }
 
@@ -60,26 +85,37 @@ type Action struct {
 
 // Slot information
 type Slot struct {
-	Start uint32 `json:"start"`
-	Slot  uint32 `json:"slot"`
+	Target string `json:"target,omitempty"`
+	Start  uint32 `json:"start"`
+	Slot   uint32 `json:"slot"`
+}
+
+// TreeNode a level of targets
+type TreeNode struct {
+	Name     string      `json:"name"`
+	Path     string      `json:"path"`
+	Seconds  uint64      `json:"seconds"`
+	Children []*TreeNode `json:"children,omitempty"`
 }
 
 func listAction(c *cli.Context) error {
 	jsonFormat := c.Bool("json")
 
-	addr := c.String("addr")
-	p := uint16(c.GlobalUint("p"))
-	client, exist, err := clientgo.NewExchClient(p, addr)
+	client, err := connect(c)
 	if err != nil {
 		return cli.NewExitError(err, 2)
 	}
-	if !exist {
-		return cli.NewExitError("service unavailable", 2)
+	defer client.Close()
+
+	// an empty filter matches all
+	filter := &service.Filter{
+		Glob:  c.String("match"),
+		Regex: c.String("regex"),
 	}
 
 	if c.Bool("actions") {
 		// get all action information
-		actions, err := getActions(client)
+		actions, err := getActions(client, filter)
 		if err != nil {
 			return cli.NewExitError(err, 1)
 		}
@@ -90,7 +126,7 @@ func listAction(c *cli.Context) error {
 		}
 	} else if c.Bool("targets") {
 		// get all target information
-		targets, err := client.GetTargets()
+		targets, err := getTargets(client, filter)
 		if err != nil {
 			return cli.NewExitError(err, 1)
 		}
@@ -99,42 +135,102 @@ func listAction(c *cli.Context) error {
 		} else {
 			printTargets(targets)
 		}
-	} else if target := c.String("slots"); len(target) != 0 {
+	} else if c.Bool("tree") {
+		// get target tree
+		req := &service.TreeRequest{
+			Prefix: c.String("prefix"),
+			Depth:  uint32(c.Uint("depth")),
+			Start:  uint32(c.Uint("start")),
+			End:    uint32(c.Uint("end")),
+		}
+		var root service.TargetNode
+		if err := client.call(treeRoute, req, &root); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		if jsonFormat {
+			printTreeJSON(&root)
+		} else {
+			printTree(&root)
+		}
+	} else if target := c.String("slots"); len(target) != 0 || c.Bool("all-slots") {
 		// get target slots
+		if len(target) == 0 && len(filter.Glob) == 0 && len(filter.Regex) == 0 {
+			return cli.NewExitError("--all-slots needs --match or --regex", 1)
+		}
 		start := uint32(c.Uint("start"))
 		end := uint32(c.Uint("end"))
 
-		starts, slots, err := client.GetSlots(target, start, end)
+		slots, err := getSlots(client, target, filter, start, end, uint32(c.Uint("page-size")))
 		if err != nil {
 			return cli.NewExitError(err, 1)
 		}
 		if jsonFormat {
-			printSlotsJSON(starts, slots)
+			printSlotsJSON(slots)
 		} else {
-			printSlots(target, starts, slots)
+			printSlots(slots)
 		}
 	}
 
 	return nil
 }
 
-func getActions(client *clientgo.ExchClient) ([]Action, error) {
-	targets, starts, lasts, err := client.GetActions()
-	if err != nil {
+func getActions(client *conn, filter *service.Filter) ([]Action, error) {
+	var all service.AllActions
+	if err := client.call(actionsRoute, filter, &all); err != nil {
 		return nil, err
 	}
 
 	var actions []Action
-	for i := 0; i < len(targets); i++ {
+	for _, a := range all.Actions {
 		actions = append(actions, Action{
-			Target: targets[i],
-			Start:  starts[i],
-			Last:   lasts[i],
+			Target: a.Target,
+			Start:  a.Start,
+			Last:   a.Last,
 		})
 	}
 	return actions, nil
 }
 
+func getTargets(client *conn, filter *service.Filter) ([]string, error) {
+	var all service.Targets
+	if err := client.call(targetsRoute, filter, &all); err != nil {
+		return nil, err
+	}
+	return all.Target, nil
+}
+
+// getSlots to get the slots page by page. With a filter, the slots of all
+// matched targets if target is empty.
+func getSlots(client *conn, target string, filter *service.Filter, start, end, pageSize uint32) ([]Slot, error) {
+	rang := &service.SlotRange{
+		Target: target,
+		Start:  start,
+		End:    end,
+		Limit:  pageSize,
+		Filter: filter,
+	}
+
+	var slots []Slot
+	for {
+		var page service.Slots
+		if err := client.call(slotsRoute, rang, &page); err != nil {
+			return nil, err
+		}
+		for _, slot := range page.Slots {
+			slots = append(slots, Slot{
+				Target: slot.Target,
+				Start:  slot.Start,
+				Slot:   slot.Slot,
+			})
+		}
+
+		if len(page.NextPageToken) == 0 {
+			return slots, nil
+		}
+		rang.PageToken = page.NextPageToken
+	}
+}
+
 func printActions(actions []Action) {
 	if actions == nil || len(actions) == 0 {
 		fmt.Println("no actions")
@@ -172,27 +268,62 @@ func printTargetsJSON(targets []string) {
 	fmt.Println(string(b))
 }
 
-func printSlots(target string, starts, slots []uint32) {
-	if starts == nil || len(starts) == 0 {
+func printSlots(slots []Slot) {
+	if len(slots) == 0 {
 		fmt.Println("no slots")
 		return
 	}
 
 	fmt.Println("slots:")
-	for i := 0; i < len(starts); i++ {
-		sT := time.Unix(int64(starts[i]), 0)
-		fmt.Printf("  %s\t\t%d\n", sT.Format("2006-01-02 15:04:05"), slots[i])
+	for _, slot := range slots {
+		sT := time.Unix(int64(slot.Start), 0)
+		if len(slot.Target) != 0 {
+			fmt.Printf("  %s\t\t%d\t\t%s\n", sT.Format("2006-01-02 15:04:05"), slot.Slot, slot.Target)
+		} else {
+			fmt.Printf("  %s\t\t%d\n", sT.Format("2006-01-02 15:04:05"), slot.Slot)
+		}
 	}
 }
 
-func printSlotsJSON(starts, slots []uint32) {
-	var slotsInfo []Slot
-	for i := 0; i < len(starts); i++ {
-		slotsInfo = append(slotsInfo, Slot{
-			Start: starts[i],
-			Slot:  slots[i],
-		})
+func printTree(root *service.TargetNode) {
+	if len(root.Children) == 0 && root.Seconds == 0 {
+		fmt.Println("no targets")
+		return
+	}
+
+	name := root.Path
+	if len(name) == 0 {
+		name = "targets"
+	}
+	fmt.Printf("%s\t\t%s\n", name, time.Duration(root.Seconds)*time.Second)
+	printTreeLevel(root.Children, "  ")
+}
+
+func printTreeLevel(nodes []*service.TargetNode, indent string) {
+	for _, node := range nodes {
+		fmt.Printf("%s%s\t\t%s\n", indent, node.Name, time.Duration(node.Seconds)*time.Second)
+		printTreeLevel(node.Children, indent+"  ")
 	}
-	b, _ := json.Marshal(map[string][]Slot{"slots": slotsInfo})
+}
+
+func toTreeNode(node *service.TargetNode) *TreeNode {
+	n := &TreeNode{
+		Name:    node.Name,
+		Path:    node.Path,
+		Seconds: node.Seconds,
+	}
+	for _, c := range node.Children {
+		n.Children = append(n.Children, toTreeNode(c))
+	}
+	return n
+}
+
+func printTreeJSON(root *service.TargetNode) {
+	b, _ := json.Marshal(map[string]*TreeNode{"tree": toTreeNode(root)})
+	fmt.Println(string(b))
+}
+
+func printSlotsJSON(slots []Slot) {
+	b, _ := json.Marshal(map[string][]Slot{"slots": slots})
 	fmt.Println(string(b))
 }
@@ -0,0 +1,45 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tracerun/tracerun/service"
+	"github.com/urfave/cli"
+)
+
+// NewReloadCMD to reload the config of the service.
+func NewReloadCMD() cli.Command {
+	return cli.Command{
+		Name:   "reload",
+		Usage:  "reload the config of the service, like on SIGHUP",
+		Action: reloadAction,
+		Flags:  connFlags,
+	}
+}
+
+func reloadAction(c *cli.Context) error {
+	client, err := connect(c)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	defer client.Close()
+
+	var result service.ReloadResult
+	if err := client.call(reloadRoute, nil, &result); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	printReload(&result)
+	return nil
+}
+
+func printReload(result *service.ReloadResult) {
+	if len(result.Changed) == 0 {
+		fmt.Println("config reloaded, nothing changed")
+	} else {
+		fmt.Printf("config reloaded, changed: %s\n", strings.Join(result.Changed, ", "))
+	}
+	if len(result.Restart) != 0 {
+		fmt.Printf("restart needed for: %s\n", strings.Join(result.Restart, ", "))
+	}
+}
@@ -0,0 +1,137 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tracerun/tracerun/service"
+	"github.com/urfave/cli"
+)
+
+// NewReportCMD to report the time spent on targets.
+func NewReportCMD() cli.Command {
+	return cli.Command{
+		Name:   "report",
+		Usage:  "report time spent on targets by hour, day, week or month",
+		Action: reportAction,
+		Flags: append([]cli.Flag{
+			cli.BoolFlag{
+				Name:  "json, j",
+				Usage: "Show result with JSON.",
+			},
+			cli.StringFlag{
+				Name:  "by, b",
+				Usage: "Bucket of the report: hour, day, week or month.",
+				Value: service.BucketDay,
+			},
+			cli.StringSliceFlag{
+				Name:  "target, t",
+				Usage: "Target to report, repeat it for several targets. All targets if not set.",
+			},
+			cli.StringFlag{
+				Name:  "prefix",
+				Usage: "Only report the targets at or below the prefix.",
+			},
+			cli.UintFlag{
+				Name:  "depth",
+				Usage: "Roll up targets to their first levels, 0 for whole targets.",
+				Value: uint(0),
+			},
+			cli.UintFlag{
+				Name:  "start, s",
+				Usage: "The start unixtime of the report, 0 for 1970-1-1 00:00:00.",
+				Value: uint(0),
+			},
+			cli.UintFlag{
+				Name:  "end, e",
+				Usage: "The end unixtime of the report, 0 for 2106-2-7 06:28:15.",
+				Value: uint(0),
+			},
+		}, connFlags...),
+	}
+}
+
+// ReportRow time spent on a target in a bucket
+type ReportRow struct {
+	Target  string `json:"target"`
+	Bucket  uint32 `json:"bucket"`
+	Seconds uint64 `json:"seconds"`
+}
+
+func reportAction(c *cli.Context) error {
+	client, err := connect(c)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	defer client.Close()
+
+	req := &service.ReportRequest{
+		Targets: c.StringSlice("target"),
+		Start:   uint32(c.Uint("start")),
+		End:     uint32(c.Uint("end")),
+		Bucket:  c.String("by"),
+		Depth:   uint32(c.Uint("depth")),
+		Prefix:  c.String("prefix"),
+	}
+	var report service.Report
+	if err := client.call(reportRoute, req, &report); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	if c.Bool("json") {
+		printReportJSON(&report)
+	} else {
+		printReport(&report, req.Bucket)
+	}
+	return nil
+}
+
+func bucketLayout(bucket string) string {
+	switch bucket {
+	case service.BucketHour:
+		return "2006-01-02 15:00"
+	case service.BucketMonth:
+		return "2006-01"
+	default:
+		return "2006-01-02"
+	}
+}
+
+func printReport(report *service.Report, bucket string) {
+	if len(report.Rows) == 0 {
+		fmt.Println("no time spent")
+		return
+	}
+
+	loc := time.FixedZone("", int(report.ZoneOffset))
+	layout := bucketLayout(bucket)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tTARGET\tTIME\n", strings.ToUpper(bucket))
+	for _, row := range report.Rows {
+		b := time.Unix(int64(row.Bucket), 0).In(loc)
+		d := time.Duration(row.Seconds) * time.Second
+		fmt.Fprintf(w, "%s\t%s\t%s\n", b.Format(layout), row.Target, d)
+	}
+	w.Flush()
+}
+
+func printReportJSON(report *service.Report) {
+	rows := []ReportRow{}
+	for _, row := range report.Rows {
+		rows = append(rows, ReportRow{
+			Target:  row.Target,
+			Bucket:  row.Bucket,
+			Seconds: row.Seconds,
+		})
+	}
+	b, _ := json.Marshal(map[string]interface{}{
+		"zone_offset": report.ZoneOffset,
+		"rows":        rows,
+	})
+	fmt.Println(string(b))
+}
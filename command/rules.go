@@ -0,0 +1,83 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/tracerun/tracerun/service"
+	"github.com/urfave/cli"
+)
+
+// NewRulesCMD to list and reload the rules of the service.
+func NewRulesCMD() cli.Command {
+	return cli.Command{
+		Name:   "rules",
+		Usage:  "list the rules to allow or deny targets",
+		Action: rulesAction,
+		Flags: append([]cli.Flag{
+			cli.BoolFlag{
+				Name:  "json, j",
+				Usage: "Show result with JSON.",
+			},
+			cli.BoolFlag{
+				Name:  "reload, r",
+				Usage: "Load the rules file again before listing.",
+			},
+		}, connFlags...),
+	}
+}
+
+// Rule to allow or deny targets
+type Rule struct {
+	Action  string `json:"action"`
+	Kind    string `json:"kind"`
+	Pattern string `json:"pattern"`
+}
+
+func rulesAction(c *cli.Context) error {
+	client, err := connect(c)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	defer client.Close()
+
+	var rules service.Rules
+	if err := client.call(rulesRoute, &service.RulesRequest{Reload: c.Bool("reload")}, &rules); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	if c.Bool("json") {
+		printRulesJSON(&rules)
+	} else {
+		printRules(&rules)
+	}
+	return nil
+}
+
+func printRules(rules *service.Rules) {
+	if len(rules.Rules) == 0 {
+		fmt.Printf("no rules in %s\n", rules.File)
+		return
+	}
+
+	fmt.Printf("rules in %s:\n", rules.File)
+	for _, ru := range rules.Rules {
+		fmt.Printf("  %s\t%s\t%s\n", ru.Action, ru.Kind, ru.Pattern)
+	}
+}
+
+func printRulesJSON(rules *service.Rules) {
+	list := []Rule{}
+	for _, ru := range rules.Rules {
+		list = append(list, Rule{
+			Action:  ru.Action,
+			Kind:    ru.Kind,
+			Pattern: ru.Pattern,
+		})
+	}
+	b, _ := json.Marshal(map[string]interface{}{
+		"file":  rules.File,
+		"rules": list,
+	})
+	fmt.Println(string(b))
+}
@@ -2,9 +2,13 @@ package command
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
+	"time"
 
+	"github.com/tracerun/tracerun/config"
 	"github.com/tracerun/tracerun/service"
 	"github.com/urfave/cli"
 )
@@ -20,12 +24,119 @@ func NewStartCMD() cli.Command {
 				Name:  "d",
 				Usage: "Run in background mode.",
 			},
+			cli.StringSliceFlag{
+				Name:  "listen",
+				Usage: "Host or \"host:port\" to listen on, repeat it for several. 127.0.0.1 if not set, \"::\" for all interfaces.",
+			},
+			cli.StringFlag{
+				Name:  "socket",
+				Usage: "Path of a Unix socket to listen on too, none if not set.",
+			},
+			cli.BoolFlag{
+				Name:  "udp",
+				Usage: "Receive actions by datagram too, on the listen hosts. Plaintext, so refused with --tls-cert.",
+			},
+			cli.UintFlag{
+				Name:  "udp-port",
+				Usage: "UDP port for datagrams, the TCP port if not set.",
+			},
+			cli.StringFlag{
+				Name:  "tls-cert",
+				Usage: "Certificate file to listen with TLS, plaintext if not set.",
+			},
+			cli.StringFlag{
+				Name:  "tls-key",
+				Usage: "Key file of the TLS certificate.",
+			},
+			cli.StringFlag{
+				Name:  "client-ca",
+				Usage: "CA file to verify client certificates, none asked if not set.",
+			},
+			cli.StringFlag{
+				Name:  "metrics",
+				Usage: "\"host:port\" to expose Prometheus metrics on /metrics, none if not set.",
+			},
+			cli.BoolFlag{
+				Name:  "no-exit",
+				Usage: "Refuse the legacy exit route, the admin command stops the service. Enabled, it takes the same token or socket as the admin route.",
+			},
+			cli.BoolFlag{
+				Name:  "auth",
+				Usage: "Ask clients for the token of the token file, generated if missing.",
+			},
+			cli.StringFlag{
+				Name:  "users",
+				Usage: "Users file of \"<user> <role> <token>\" lines, read with --auth, \"<db>.users\" if not set.",
+			},
+			cli.StringFlag{
+				Name:  "socket-mode",
+				Value: "0600",
+				Usage: "Permissions of the Unix socket file, in octal.",
+			},
+			cli.DurationFlag{
+				Name:  "read-timeout",
+				Value: service.DefaultReadTimeout,
+				Usage: "Close a connection without frames for this long.",
+			},
+			cli.DurationFlag{
+				Name:  "check-interval",
+				Value: service.DefaultCheckInterval,
+				Usage: "Interval between two checks of expired actions.",
+			},
+			cli.IntFlag{
+				Name:  "queue-size",
+				Value: 200,
+				Usage: "Capacity of the action queue.",
+			},
+			cli.StringFlag{
+				Name:  "queue-policy",
+				Value: service.PolicyBlock,
+				Usage: "What to do when the action queue is full: block, drop-oldest, drop-newest or busy.",
+			},
+			cli.DurationFlag{
+				Name:  "max-skew",
+				Value: 5 * time.Minute,
+				Usage: "How far in the future a client timestamp is accepted.",
+			},
+			cli.DurationFlag{
+				Name:  "max-backdate",
+				Value: 7 * 24 * time.Hour,
+				Usage: "How far in the past a client timestamp is accepted.",
+			},
+			cli.StringFlag{
+				Name:  "separator",
+				Value: service.DefaultSeparator,
+				Usage: "Separator between the levels of a target.",
+			},
+			cli.StringFlag{
+				Name:  "rules",
+				Usage: "File of the rules to allow or deny targets, \"<db>.rules\" if not set.",
+			},
+			cli.StringFlag{
+				Name:  "meta",
+				Usage: "File the metadata of the actions is appended to, as JSON lines, \"<db>.meta\" if not set.",
+			},
+			cli.BoolFlag{
+				Name:  "expand-home",
+				Usage: "Expand a leading \"~\" of targets to the home folder.",
+			},
+			cli.BoolFlag{
+				Name:  "clean",
+				Usage: "Clean targets like paths: no empty, \".\" or trailing levels, \"..\" resolved.",
+			},
+			cli.StringSliceFlag{
+				Name:  "rewrite",
+				Usage: "Rewrite a target prefix as \"from=to\", repeat it for several, the first matched is applied.",
+			},
+			cli.BoolFlag{
+				Name:  "lower",
+				Usage: "Lowercase targets, for case-insensitive file systems.",
+			},
 		},
 	}
 }
 
 func startAction(c *cli.Context) error {
-	p := c.GlobalUint("p")
 	if c.Bool("d") {
 		idx := 0
 		for i := 0; i < len(os.Args); i++ {
@@ -52,8 +163,87 @@ func startAction(c *cli.Context) error {
 			return err
 		}
 	} else {
-		service.Start(uint16(p), c.GlobalString("db"))
+		cfg, err := serviceConfig(c)
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		if loader, ok := c.App.Metadata["config"].(*config.Loader); ok {
+			cfg.Reload = func() (service.Config, error) {
+				nc, err := loader.Reload(c, c.Command.Name)
+				if err != nil {
+					return service.Config{}, err
+				}
+				return serviceConfig(nc)
+			}
+		}
+		if err := service.Start(cfg); err != nil {
+			return cli.NewExitError(err, 1)
+		}
 	}
 
 	return nil
 }
+
+// serviceConfig to get the config of the service from the flags.
+func serviceConfig(c *cli.Context) (service.Config, error) {
+	normalize, err := normalizeConfig(c)
+	if err != nil {
+		return service.Config{}, err
+	}
+	mode, err := strconv.ParseUint(c.String("socket-mode"), 8, 32)
+	if err != nil {
+		return service.Config{}, fmt.Errorf("wrong socket mode %q", c.String("socket-mode"))
+	}
+	return service.Config{
+		Port:          uint16(c.GlobalUint("p")),
+		Listen:        c.StringSlice("listen"),
+		Socket:        c.String("socket"),
+		SocketMode:    os.FileMode(mode),
+		UDP:           c.Bool("udp"),
+		UDPPort:       uint16(c.Uint("udp-port")),
+		DisableExit:   c.Bool("no-exit"),
+		Metrics:       c.String("metrics"),
+		Auth:          c.Bool("auth"),
+		TokenFile:     c.GlobalString("token-file"),
+		UsersFile:     c.String("users"),
+		TLSCert:       c.String("tls-cert"),
+		TLSKey:        c.String("tls-key"),
+		ClientCA:      c.String("client-ca"),
+		ReadTimeout:   c.Duration("read-timeout"),
+		CheckInterval: c.Duration("check-interval"),
+		DBFolder:      c.GlobalString("db"),
+		QueueSize:     c.Int("queue-size"),
+		QueuePolicy:   c.String("queue-policy"),
+
+		MaxClockSkew: c.Duration("max-skew"),
+		MaxBackdate:  c.Duration("max-backdate"),
+		Separator:    c.String("separator"),
+		RulesFile:    c.String("rules"),
+		MetaFile:     c.String("meta"),
+		Normalize:    normalize,
+		Debug:        c.GlobalBool("debug"),
+	}, nil
+}
+
+// normalizeConfig to get the normalize steps from the flags.
+func normalizeConfig(c *cli.Context) (service.Normalize, error) {
+	n := service.Normalize{
+		Clean: c.Bool("clean"),
+		Lower: c.Bool("lower"),
+	}
+	if c.Bool("expand-home") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return n, err
+		}
+		n.Home = home
+	}
+	for _, s := range c.StringSlice("rewrite") {
+		r, err := service.ParseRewrite(s)
+		if err != nil {
+			return n, err
+		}
+		n.Rewrites = append(n.Rewrites, r)
+	}
+	return n, nil
+}
@@ -0,0 +1,300 @@
+// Package config reads the settings of the command line from a JSON file and
+// environment variables. A flag set in the command line wins over its
+// environment variable, which wins over the file, which wins over the
+// default of the flag.
+//
+// The keys of the file are the flag names, the flags of a command are in an
+// object under the command name:
+//
+//	{
+//	  "db": "/var/lib/tracerun",
+//	  "start": {"queue-size": 1000, "rewrite": ["~/work/=work/"]},
+//	  "list": {"addr": "10.0.0.2"}
+//	}
+//
+// The environment variable of a global flag is TRACERUN_<FLAG>, the one of a
+// command flag is TRACERUN_<COMMAND>_<FLAG>, uppercased with "-" as "_".
+// Values of slice flags in the environment are separated by ",".
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// EnvPrefix of the environment variables
+const EnvPrefix = "TRACERUN_"
+
+// Settings read from a file, by flag or command name.
+type Settings map[string]json.RawMessage
+
+// SearchPath to find a config file when none is given, the first one found
+// is used.
+func SearchPath() []string {
+	paths := []string{"tracerun.json"}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "tracerun", "config.json"))
+	}
+	return append(paths, "/etc/tracerun/config.json")
+}
+
+// Find the config file: path if not empty, or the first one found in the
+// search path. An empty path without error if there is none.
+func Find(path string) (string, error) {
+	if len(path) != 0 {
+		_, err := os.Stat(path)
+		return path, err
+	}
+	for _, p := range SearchPath() {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", nil
+}
+
+// Load the settings of a file, no settings for an empty path.
+func Load(path string) (Settings, error) {
+	settings := make(Settings)
+	if len(path) == 0 {
+		return settings, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &settings); err != nil {
+		return nil, fmt.Errorf("config %s: %v", path, err)
+	}
+	return settings, nil
+}
+
+// Section to get the settings of a command.
+func (s Settings) Section(command string) (Settings, error) {
+	section := make(Settings)
+	raw, ok := s[command]
+	if !ok {
+		return section, nil
+	}
+	if err := json.Unmarshal(raw, &section); err != nil {
+		return nil, fmt.Errorf("config of %s: %v", command, err)
+	}
+	return section, nil
+}
+
+// Apply the environment variables and the settings to the flags not set in
+// the command line. envPrefix is prepended to the flag names for the
+// environment variables.
+func Apply(c *cli.Context, flags []cli.Flag, settings Settings, envPrefix string) error {
+	for _, f := range flags {
+		names := flagNames(f)
+		if isSet(c, names) {
+			continue
+		}
+		name := names[0]
+
+		values, err := lookup(name, settings, envPrefix)
+		if err != nil {
+			return err
+		}
+		for _, v := range values {
+			if err := c.Set(name, v); err != nil {
+				return fmt.Errorf("config of %s: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// lookup the values of a flag, in the environment first.
+func lookup(name string, settings Settings, envPrefix string) ([]string, error) {
+	env := envPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+	if v, ok := os.LookupEnv(env); ok {
+		return strings.Split(v, ","), nil
+	}
+
+	raw, ok := settings[name]
+	if !ok {
+		return nil, nil
+	}
+	values, err := rawValues(raw)
+	if err != nil {
+		return nil, fmt.Errorf("config of %s: %v", name, err)
+	}
+	return values, nil
+}
+
+// rawValues to get a JSON value as flag values, an array has a value for
+// each item.
+func rawValues(raw json.RawMessage) ([]string, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		items = []json.RawMessage{raw}
+	}
+
+	var values []string
+	for _, item := range items {
+		var v interface{}
+		if err := json.Unmarshal(item, &v); err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case string:
+			values = append(values, v)
+		case bool, float64:
+			values = append(values, string(item))
+		default:
+			return nil, fmt.Errorf("unsupported value %s", item)
+		}
+	}
+	return values, nil
+}
+
+func flagNames(f cli.Flag) []string {
+	var names []string
+	for _, name := range strings.Split(f.GetName(), ",") {
+		names = append(names, strings.TrimSpace(name))
+	}
+	return names
+}
+
+func isSet(c *cli.Context, names []string) bool {
+	for _, name := range names {
+		if c.IsSet(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Loader applies the config file and the environment to an app and its
+// commands, and again on reload. The flags set in the command line are
+// remembered to keep winning on reload.
+type Loader struct {
+	// File loaded, empty if none
+	File string
+
+	path     string
+	settings Settings
+	explicit map[string]map[string]bool
+}
+
+// NewLoader to create a loader.
+func NewLoader() *Loader {
+	return &Loader{explicit: make(map[string]map[string]bool)}
+}
+
+// Global to load the config file given by the "config" flag, and apply it
+// to the global flags of the app.
+func (l *Loader) Global(c *cli.Context) error {
+	l.path = c.GlobalString("config")
+	if err := l.load(); err != nil {
+		return err
+	}
+	l.explicit[""] = explicit(c, c.App.Flags)
+	return Apply(c, c.App.Flags, l.settings, EnvPrefix)
+}
+
+// Command to apply the config to the flags of a command, subcommands use the
+// name of their top command.
+func (l *Loader) Command(c *cli.Context, name string) error {
+	section, err := l.settings.Section(name)
+	if err != nil {
+		return err
+	}
+	l.explicit[name] = explicit(c, c.Command.Flags)
+	return Apply(c, c.Command.Flags, section, commandPrefix(name))
+}
+
+// Reload to read the config file again, and get a new context of the
+// command with the flags applied like in Global and Command.
+func (l *Loader) Reload(c *cli.Context, name string) (*cli.Context, error) {
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+
+	global, err := renew(c.Parent(), nil, c.App.Flags, l.explicit[""])
+	if err != nil {
+		return nil, err
+	}
+	if err := Apply(global, c.App.Flags, l.settings, EnvPrefix); err != nil {
+		return nil, err
+	}
+
+	section, err := l.settings.Section(name)
+	if err != nil {
+		return nil, err
+	}
+	nc, err := renew(c, global, c.Command.Flags, l.explicit[name])
+	if err != nil {
+		return nil, err
+	}
+	nc.Command = c.Command
+	return nc, Apply(nc, c.Command.Flags, section, commandPrefix(name))
+}
+
+func (l *Loader) load() error {
+	file, err := Find(l.path)
+	if err != nil {
+		return err
+	}
+	settings, err := Load(file)
+	if err != nil {
+		return err
+	}
+	l.File, l.settings = file, settings
+	return nil
+}
+
+func commandPrefix(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1)) + "_"
+}
+
+// explicit to get the names of the flags set in the command line.
+func explicit(c *cli.Context, flags []cli.Flag) map[string]bool {
+	set := make(map[string]bool)
+	for _, f := range flags {
+		names := flagNames(f)
+		if isSet(c, names) {
+			set[names[0]] = true
+		}
+	}
+	return set
+}
+
+// renew to get a context of flags with their defaults, but the explicit
+// ones copied from c.
+func renew(c *cli.Context, parent *cli.Context, flags []cli.Flag, explicit map[string]bool) (*cli.Context, error) {
+	set := flag.NewFlagSet(c.Command.Name, flag.ContinueOnError)
+	for _, f := range flags {
+		f.Apply(set)
+	}
+	nc := cli.NewContext(c.App, set, parent)
+
+	for name := range explicit {
+		var values []string
+		switch v := c.Generic(name).(type) {
+		case *cli.StringSlice:
+			values = v.Value()
+		case *cli.IntSlice:
+			for _, i := range v.Value() {
+				values = append(values, fmt.Sprint(i))
+			}
+		case flag.Value:
+			values = []string{v.String()}
+		}
+		for _, v := range values {
+			if err := nc.Set(name, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nc, nil
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+func TestApply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{
+		"db": "file-db",
+		"start": {"queue-size": 500, "queue-policy": "busy", "rewrite": ["a/=b/", "c/=d/"], "lower": true}
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	section, err := settings.Section("start")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flags := []cli.Flag{
+		cli.IntFlag{Name: "queue-size", Value: 200},
+		cli.StringFlag{Name: "queue-policy", Value: "block"},
+		cli.StringFlag{Name: "separator", Value: "/"},
+		cli.StringSliceFlag{Name: "rewrite"},
+		cli.BoolFlag{Name: "lower, l"},
+	}
+	set := flag.NewFlagSet("start", flag.ContinueOnError)
+	for _, f := range flags {
+		f.Apply(set)
+	}
+	// the command line wins over the environment, which wins over the file
+	if err := set.Parse([]string{"-queue-size", "300"}); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("TRACERUN_START_QUEUE_SIZE", "400")
+	os.Setenv("TRACERUN_START_QUEUE_POLICY", "drop-oldest")
+	defer os.Unsetenv("TRACERUN_START_QUEUE_SIZE")
+	defer os.Unsetenv("TRACERUN_START_QUEUE_POLICY")
+
+	c := cli.NewContext(nil, set, nil)
+	c.Command = cli.Command{Name: "start", Flags: flags}
+	if err := Apply(c, flags, section, EnvPrefix+"START_"); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := c.Int("queue-size"); v != 300 {
+		t.Errorf("expect queue-size from the command line, got %d", v)
+	}
+	if v := c.String("queue-policy"); v != "drop-oldest" {
+		t.Errorf("expect queue-policy from the environment, got %s", v)
+	}
+	if v := c.String("separator"); v != "/" {
+		t.Errorf("expect the default separator, got %s", v)
+	}
+	if v := c.StringSlice("rewrite"); len(v) != 2 || v[1] != "c/=d/" {
+		t.Errorf("expect rewrites from the file, got %v", v)
+	}
+	if !c.Bool("lower") {
+		t.Error("expect lower from the file")
+	}
+
+	if _, err := rawValues(json.RawMessage(`{"a": 1}`)); err == nil {
+		t.Error("an object should not be a flag value")
+	}
+	if _, err := Find(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("a missing config given should fail")
+	}
+}
+
+func TestLoaderReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"db": "one", "start": {"queue-size": 500, "separator": "."}}`)
+
+	app := cli.NewApp()
+	app.Flags = []cli.Flag{
+		cli.StringFlag{Name: "config"},
+		cli.StringFlag{Name: "db", Value: "tracerun"},
+	}
+	flags := []cli.Flag{
+		cli.IntFlag{Name: "queue-size", Value: 200},
+		cli.StringFlag{Name: "separator", Value: "/"},
+	}
+
+	globalSet := flag.NewFlagSet("tracerun", flag.ContinueOnError)
+	for _, f := range app.Flags {
+		f.Apply(globalSet)
+	}
+	if err := globalSet.Parse([]string{"-config", path}); err != nil {
+		t.Fatal(err)
+	}
+	global := cli.NewContext(app, globalSet, nil)
+
+	set := flag.NewFlagSet("start", flag.ContinueOnError)
+	for _, f := range flags {
+		f.Apply(set)
+	}
+	if err := set.Parse([]string{"-separator", ":"}); err != nil {
+		t.Fatal(err)
+	}
+	c := cli.NewContext(app, set, global)
+	c.Command = cli.Command{Name: "start", Flags: flags}
+
+	l := NewLoader()
+	if err := l.Global(global); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Command(c, "start"); err != nil {
+		t.Fatal(err)
+	}
+	if c.GlobalString("db") != "one" || c.Int("queue-size") != 500 || c.String("separator") != ":" {
+		t.Fatalf("wrong flags %s %d %s", c.GlobalString("db"), c.Int("queue-size"), c.String("separator"))
+	}
+
+	// removed from the file back to the default, the command line still wins
+	write(`{"db": "two", "start": {"separator": "."}}`)
+	nc, err := l.Reload(c, "start")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nc.GlobalString("db") != "two" || nc.Int("queue-size") != 200 || nc.String("separator") != ":" {
+		t.Errorf("wrong reloaded flags %s %d %s", nc.GlobalString("db"), nc.Int("queue-size"), nc.String("separator"))
+	}
+
+	write(`{"db": `)
+	if _, err := l.Reload(c, "start"); err == nil {
+		t.Error("a wrong file should fail")
+	}
+}
@@ -9,6 +9,9 @@ import (
 var (
 	// L the zap logger
 	L *zap.Logger
+
+	// level of L, changed by SetDebug
+	level = zap.NewAtomicLevel()
 )
 
 type key int
@@ -22,11 +25,8 @@ const requestIDKey key = 0
 func InitLogger(debug, nostd bool, logPath string) {
 	var cfg zap.Config
 
-	dyn := zap.NewAtomicLevel()
-	if debug {
-		dyn.SetLevel(zap.DebugLevel)
-	}
-	cfg.Level = dyn
+	SetDebug(debug)
+	cfg.Level = level
 	cfg.EncoderConfig.LevelKey = "lvl"
 	cfg.EncoderConfig.MessageKey = "msg"
 	cfg.EncoderConfig.TimeKey = "timestamp"
@@ -51,3 +51,12 @@ func InitLogger(debug, nostd bool, logPath string) {
 	}
 	L.Debug("log path", zap.Strings("paths", paths))
 }
+
+// SetDebug to change the level of L, to debug or to info.
+func SetDebug(debug bool) {
+	if debug {
+		level.SetLevel(zap.DebugLevel)
+	} else {
+		level.SetLevel(zap.InfoLevel)
+	}
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/tracerun/tracerun/command"
+	"github.com/tracerun/tracerun/config"
 	"github.com/tracerun/tracerun/lg"
 	"github.com/urfave/cli"
+	"go.uber.org/zap"
 )
 
 func main() {
@@ -15,6 +18,10 @@ func main() {
 	app.Version = "0.0.1"
 
 	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "config",
+			Usage: fmt.Sprintf("Path for the JSON config file, the first of %v found if not set.", config.SearchPath()),
+		},
 		cli.BoolFlag{
 			Name:  "debug",
 			Usage: "Run in debug level.",
@@ -37,12 +44,26 @@ func main() {
 			Value: 19869,
 			Usage: "TCP port.",
 		},
+		cli.StringFlag{
+			Name:  "token",
+			Usage: "Token to authenticate with the service, visible in ps and the shell history. Prefer --token-file or TRACERUN_TOKEN.",
+		},
+		cli.StringFlag{
+			Name:  "token-file",
+			Usage: "Path for the token file, \"<db>.token\" if not set.",
+		},
 	}
 
+	loader := config.NewLoader()
+	app.Metadata = map[string]interface{}{"config": loader}
 	app.Before = func(c *cli.Context) error {
+		if err := loader.Global(c); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+
 		logPath := c.GlobalString("o")
 		lg.InitLogger(c.GlobalBool("debug"), c.GlobalBool("nostd"), logPath)
-		lg.L.Debug("logger initialized")
+		lg.L.Debug("logger initialized", zap.String("config", loader.File))
 		return nil
 	}
 
@@ -50,7 +71,34 @@ func main() {
 		command.NewStartCMD(),
 		command.NewAddCMD(),
 		command.NewListCMD(),
+		command.NewReportCMD(),
+		command.NewRulesCMD(),
+		command.NewExpiryCMD(),
+		command.NewReloadCMD(),
+		command.NewAdminCMD(),
+	}
+
+	for i := range app.Commands {
+		configure(&app.Commands[i], app.Commands[i].Name, loader)
 	}
 
 	app.Run(os.Args)
 }
+
+// configure a command and its subcommands to apply the config of name to
+// their flags not set.
+func configure(cmd *cli.Command, name string, loader *config.Loader) {
+	for i := range cmd.Subcommands {
+		configure(&cmd.Subcommands[i], name, loader)
+	}
+	if len(cmd.Flags) == 0 {
+		return
+	}
+
+	cmd.Before = func(c *cli.Context) error {
+		if err := loader.Command(c, name); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tracerun/tracerun/lg"
+	"go.uber.org/zap"
+)
+
+// Operations of the admin route.
+const (
+	// AdminShutdown to stop the service like on SIGTERM
+	AdminShutdown = "shutdown"
+	// AdminRestartListener to listen again on the TCP addresses
+	AdminRestartListener = "restart-listener"
+	// AdminFlush to write the queued actions now
+	AdminFlush = "flush"
+	// AdminReload to reload the config like on SIGHUP
+	AdminReload = "reload"
+)
+
+var (
+	// ErrControl the admin route called by a session not trusted
+	ErrControl = errors.New("admin route needs an admin token, or the Unix socket as the service user")
+	// ErrExitDisabled the legacy exit route called while disabled
+	ErrExitDisabled = errors.New("exit route disabled, use the admin route")
+	// ErrStopping an operation refused while the service stops
+	ErrStopping = errors.New("service is stopping")
+
+	// exitDisabled to refuse the legacy exit route, guarded by settingsMu
+	exitDisabled bool
+)
+
+// controlAllowed to check whether a session may call the admin route: an
+// admin token with authentication on, or a local peer of the Unix socket.
+func controlAllowed(w io.Writer) bool {
+	s, ok := w.(*session)
+	if !ok {
+		return false
+	}
+	if s.local {
+		return true
+	}
+	authMu.RLock()
+	on := len(token) != 0
+	authMu.RUnlock()
+	return on && s.perm >= PermAdmin
+}
+
+// callerFields to log who called a route.
+func callerFields(w io.Writer) []zap.Field {
+	s, ok := w.(*session)
+	if !ok {
+		return nil
+	}
+	return []zap.Field{
+		zap.String("from", s.remote),
+		zap.String("user", s.user),
+		zap.String("host", s.host),
+		zap.Bool("local", s.local),
+	}
+}
+
+// flushActions to have receiveActions write the actions queued, the count
+// of them is returned.
+func flushActions() (int, error) {
+	done := make(chan int, 1)
+	select {
+	case flushChan <- done:
+	case <-quitChan:
+		return 0, ErrStopping
+	}
+	return <-done, nil
+}
+
+// restartListeners to listen again on the addresses of the running config.
+func restartListeners() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	if server == nil || closing {
+		return ErrStopping
+	}
+	return restartListener(running)
+}
+
+// control to run an admin operation.
+func control(req *AdminRequest) (*AdminResult, error) {
+	result := &AdminResult{Op: req.Op}
+	switch req.Op {
+	case AdminShutdown:
+		// stopped after the reply
+	case AdminRestartListener:
+		if err := restartListeners(); err != nil {
+			return nil, err
+		}
+	case AdminFlush:
+		flushed, err := flushActions()
+		if err != nil {
+			return nil, err
+		}
+		result.Flushed = uint32(flushed)
+	case AdminReload:
+		reload, err := reloadConfig()
+		if err != nil {
+			return nil, err
+		}
+		result.Reload = reload
+	default:
+		return nil, fmt.Errorf("unknown admin operation %q", req.Op)
+	}
+	return result, nil
+}
+
+// checkExit to audit a call of the legacy exit route. It's refused if
+// disabled, or to a session not allowed to control like on the admin route.
+func checkExit(w io.Writer) error {
+	settingsMu.RLock()
+	disabled := exitDisabled
+	settingsMu.RUnlock()
+
+	var err error
+	if disabled {
+		err = ErrExitDisabled
+	} else if !controlAllowed(w) {
+		err = ErrControl
+	}
+	if err != nil {
+		lg.L.Warn("exit route refused", append(callerFields(w), zap.Error(err))...)
+		return err
+	}
+	lg.L.Warn("exit route called", callerFields(w)...)
+	return nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/tracerun/tracerun/lg"
+)
+
+func TestControlAllowed(t *testing.T) {
+	defer setToken("")
+
+	setToken("")
+	if controlAllowed(newSession(io.Discard)) {
+		t.Error("a session without authentication should not control")
+	}
+	if !controlAllowed(&session{Writer: io.Discard, local: true}) {
+		t.Error("a local peer should control")
+	}
+
+	setToken("secret")
+	if controlAllowed(newSession(io.Discard)) {
+		t.Error("a session not authenticated should not control")
+	}
+	if !controlAllowed(&session{Writer: io.Discard, perm: PermAdmin}) {
+		t.Error("an admin token should control")
+	}
+	if controlAllowed(&session{Writer: io.Discard, perm: PermWrite, user: "alice"}) {
+		t.Error("a user token should not control")
+	}
+}
+
+func TestFlushActions(t *testing.T) {
+	lg.InitLogger(false, true, "")
+	fillQueue(t, PolicyBlock)
+	accepted := atomic.LoadUint64(&acceptedCount)
+
+	// receiveActions writing to a slice
+	var written []string
+	go func() {
+		done := <-flushChan
+		done <- flushQueue(func(a *act) { written = append(written, a.target) })
+	}()
+
+	flushed, err := flushActions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flushed != 2 || strings.Join(written, ",") != "a,b" {
+		t.Errorf("expect a and b flushed, got %d %v", flushed, written)
+	}
+	if len(actionChan) != 0 || atomic.LoadUint64(&acceptedCount) != accepted {
+		t.Error("a flush should not queue or count anything")
+	}
+}
+
+func TestExitDisabled(t *testing.T) {
+	lg.InitLogger(false, true, "")
+	defer func() { exitDisabled = false }()
+
+	exitDisabled = true
+	var buf bytes.Buffer
+	exit(nil, &buf)
+	_, route, err := ReadOne(&buf)
+	if err != nil || route != 255 {
+		t.Errorf("expect an error reply, got route %d %v", route, err)
+	}
+	select {
+	case <-stopChan:
+		t.Error("a disabled exit should not stop")
+	default:
+	}
+
+	// enabled, only for a session allowed to control
+	exitDisabled = false
+	buf.Reset()
+	exit(nil, newSession(&buf))
+	_, route, err = ReadOne(&buf)
+	if err != nil || route != 255 {
+		t.Errorf("expect a TCP peer refused without token, got route %d %v", route, err)
+	}
+	exit(nil, &session{Writer: &buf, local: true})
+	select {
+	case <-stopChan:
+	default:
+		t.Error("a local peer should stop")
+	}
+}
+
+func TestAdminRoute(t *testing.T) {
+	lg.InitLogger(false, true, "")
+	router := map[uint8]RouteFunc{9: admin}
+
+	call := func(conn net.Conn) string {
+		buf, _ := proto.Marshal(&AdminRequest{Op: "nothing"})
+		conn.Write(append(GenerateHeaderBuf(uint16(len(buf)), 9), buf...))
+		data, _, err := ReadOne(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var errMsg ErrorMessage
+		proto.Unmarshal(data, &errMsg)
+		return errMsg.Message
+	}
+
+	s := NewTCPServer(8881, router)
+	s.Hosts = []string{"127.0.0.1"}
+	if err := s.listen(); err != nil {
+		t.Fatal(err)
+	}
+	go s.serve()
+	defer s.Shutdown(time.Second)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:8881")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if msg := call(conn); msg != ErrControl.Error() {
+		t.Errorf("expect TCP refused without token, got %q", msg)
+	}
+
+	if runtime.GOOS != "linux" {
+		return
+	}
+	path := filepath.Join(t.TempDir(), "tracerun.sock")
+	us := NewUnixServer(path, 0600, router)
+	if err := us.listen(); err != nil {
+		t.Fatal(err)
+	}
+	go us.serve()
+	defer us.Shutdown(time.Second)
+
+	uconn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer uconn.Close()
+	if msg := call(uconn); !strings.HasPrefix(msg, "unknown admin operation") {
+		t.Errorf("expect the local peer allowed, got %q", msg)
+	}
+}
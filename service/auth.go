@@ -0,0 +1,161 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Permissions of a route, a session holding a permission can call the
+// routes of it and below.
+const (
+	// PermOpen routes need no token
+	PermOpen = iota
+	// PermRead routes read the db
+	PermRead
+	// PermWrite routes add or change data
+	PermWrite
+	// PermAdmin routes control the service
+	PermAdmin
+)
+
+const (
+	// tokenBytes of a generated token
+	tokenBytes = 32
+	authRoute  = uint8(8)
+)
+
+var (
+	// ErrUnauthorized a route called without the permission
+	ErrUnauthorized = errors.New("unauthorized, authenticate first")
+	// ErrWrongToken an authentication with a wrong token
+	ErrWrongToken = errors.New("wrong token")
+
+	// routePerms the permission of each route, PermAdmin if not listed
+	routePerms = map[uint8]int{
+		0:  PermAdmin,
+		1:  PermOpen,
+		2:  PermRead,
+		3:  PermRead,
+		4:  PermOpen,
+		5:  PermAdmin,
+		6:  PermAdmin,
+		7:  PermAdmin,
+		8:  PermOpen,
+		9:  PermAdmin,
+		10: PermWrite,
+		11: PermRead,
+		12: PermWrite,
+		13: PermWrite,
+		14: PermWrite,
+		20: PermRead,
+		21: PermRead,
+		22: PermRead,
+		23: PermRead,
+		24: PermRead,
+	}
+
+	authMu sync.RWMutex
+	// token to authenticate, empty if authentication is off
+	token string
+)
+
+// routePerm to get the permission needed by a route.
+func routePerm(route uint8) int {
+	if perm, ok := routePerms[route]; ok {
+		return perm
+	}
+	return PermAdmin
+}
+
+// setToken to turn authentication on, or off with an empty token.
+func setToken(t string) {
+	authMu.Lock()
+	token = t
+	authMu.Unlock()
+}
+
+// defaultPerm to get the permission of a session not authenticated.
+func defaultPerm() int {
+	authMu.RLock()
+	defer authMu.RUnlock()
+	if len(token) == 0 {
+		return PermAdmin
+	}
+	return PermOpen
+}
+
+// authenticate a token, the account of the token file or of a user.
+func authenticate(t string) (*account, error) {
+	authMu.RLock()
+	defer authMu.RUnlock()
+
+	if len(token) == 0 {
+		return &account{perm: PermAdmin}, nil
+	}
+	var found *account
+	if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+		found = &account{perm: PermAdmin}
+	}
+	// all the tokens are compared, to take the same time for any of them
+	for userToken, acc := range users {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(userToken)) == 1 {
+			found = acc
+		}
+	}
+	if found == nil {
+		return nil, ErrWrongToken
+	}
+	return found, nil
+}
+
+// loadToken to read the token of a file, one is generated if the file is
+// missing. The file must be readable by its owner only.
+func loadToken(path string) (string, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err == nil {
+		buf := make([]byte, tokenBytes)
+		if _, err := rand.Read(buf); err != nil {
+			f.Close()
+			return "", err
+		}
+		t := hex.EncodeToString(buf)
+		if _, err := f.WriteString(t + "\n"); err != nil {
+			f.Close()
+			return "", err
+		}
+		return t, f.Close()
+	}
+	if !os.IsExist(err) {
+		return "", err
+	}
+
+	b, err := readPrivate(path)
+	if err != nil {
+		return "", err
+	}
+	t := strings.TrimSpace(string(b))
+	if len(t) == 0 {
+		return "", fmt.Errorf("empty token file %s", path)
+	}
+	return t, nil
+}
+
+// readPrivate to read a file holding tokens, which must be readable by its
+// owner only.
+func readPrivate(path string) ([]byte, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("file %s is accessible by others, mode %o", path, fi.Mode().Perm())
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/tracerun/tracerun/lg"
+)
+
+func TestLoadToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tracerun.token")
+
+	generated, err := loadToken(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(generated) != tokenBytes*2 {
+		t.Errorf("wrong token generated %q", generated)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := fi.Mode().Perm(); mode != 0600 {
+		t.Errorf("expect mode 0600, got %o", mode)
+	}
+
+	if got, err := loadToken(path); err != nil || got != generated {
+		t.Errorf("expect the token kept, got %q %v", got, err)
+	}
+
+	os.Chmod(path, 0644)
+	if _, err := loadToken(path); err == nil {
+		t.Error("a token readable by others should fail")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	defer setToken("")
+
+	setToken("")
+	if perm := defaultPerm(); perm != PermAdmin {
+		t.Errorf("expect admin without auth, got %d", perm)
+	}
+
+	setToken("secret")
+	if perm := defaultPerm(); perm != PermOpen {
+		t.Errorf("expect open with auth, got %d", perm)
+	}
+	if _, err := authenticate("wrong"); err != ErrWrongToken {
+		t.Errorf("expect wrong token, got %v", err)
+	}
+	if acc, err := authenticate("secret"); err != nil || acc.perm != PermAdmin || len(acc.user) != 0 {
+		t.Errorf("expect admin without user, got %v %v", acc, err)
+	}
+
+	if routePerm(1) != PermOpen || routePerm(20) != PermRead || routePerm(10) != PermWrite || routePerm(200) != PermAdmin {
+		t.Error("wrong route permissions")
+	}
+}
+
+func TestAuthTCP(t *testing.T) {
+	lg.InitLogger(false, true, "")
+	setToken("secret")
+	defer setToken("")
+
+	router := map[uint8]RouteFunc{
+		8:  auth,
+		20: func(b []byte, w io.Writer) { writeMessage(w, 20, &Targets{Target: []string{"a.go"}}) },
+	}
+	s := NewTCPServer(8879, router)
+	if err := s.listen(); err != nil {
+		t.Fatal(err)
+	}
+	go s.serve()
+	defer s.Shutdown(time.Second)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:8879")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	send := func(route uint8, msg proto.Message) uint8 {
+		buf, _ := proto.Marshal(msg)
+		conn.Write(append(GenerateHeaderBuf(uint16(len(buf)), route), buf...))
+		_, reply, err := ReadOne(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return reply
+	}
+
+	if reply := send(20, &Filter{}); reply != 255 {
+		t.Errorf("expect unauthorized, got route %d", reply)
+	}
+	if reply := send(8, &AuthRequest{Token: "wrong"}); reply != 255 {
+		t.Errorf("expect a wrong token, got route %d", reply)
+	}
+	if reply := send(8, &AuthRequest{Token: "secret"}); reply != 8 {
+		t.Errorf("expect authenticated, got route %d", reply)
+	}
+	if reply := send(20, &Filter{}); reply != 20 {
+		t.Errorf("expect targets once authenticated, got route %d", reply)
+	}
+}
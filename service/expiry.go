@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// MinCheckInterval the shortest interval between two checks of expired
+// actions.
+const MinCheckInterval = time.Second
+
+// ErrCheckInterval an interval shorter than MinCheckInterval
+var ErrCheckInterval = errors.New("check interval shorter than 1s")
+
+var (
+	expiryMu sync.Mutex
+	// checkInterval between two checks of expired actions
+	checkInterval = DefaultCheckInterval
+	// intervalChan tells checkActions the interval changed
+	intervalChan = make(chan struct{}, 1)
+
+	lastCheck    time.Time
+	lastExpired  uint32
+	lastDuration time.Duration
+	checkCount   uint64
+	// checkTotal the time spent by all the checks
+	checkTotal time.Duration
+
+	// writeMu serializes writing actions and checking expirations, so a
+	// check counts only the actions it closed
+	writeMu sync.Mutex
+	// openTargets with an open action as of the last write or check,
+	// guarded by writeMu
+	openTargets = make(map[string]bool)
+)
+
+// setCheckInterval to change the interval, checkActions uses it from now on.
+func setCheckInterval(d time.Duration) error {
+	if d < MinCheckInterval {
+		return ErrCheckInterval
+	}
+
+	expiryMu.Lock()
+	checkInterval = d
+	expiryMu.Unlock()
+
+	select {
+	case intervalChan <- struct{}{}:
+	default:
+		// checkActions is not told yet
+	}
+	return nil
+}
+
+func getCheckInterval() time.Duration {
+	expiryMu.Lock()
+	defer expiryMu.Unlock()
+	return checkInterval
+}
+
+// recordCheck to keep the result of a check.
+func recordCheck(at time.Time, expired uint32, d time.Duration) {
+	expiryMu.Lock()
+	lastCheck, lastExpired, lastDuration = at, expired, d
+	checkCount++
+	checkTotal += d
+	expiryMu.Unlock()
+}
+
+// expiryStats to get the interval and the last check.
+func expiryStats() *Expiry {
+	expiryMu.Lock()
+	defer expiryMu.Unlock()
+
+	e := &Expiry{
+		Interval:     uint32(checkInterval / time.Second),
+		Checks:       checkCount,
+		LastExpired:  lastExpired,
+		LastDuration: uint32(lastDuration / time.Millisecond),
+	}
+	if !lastCheck.IsZero() {
+		e.LastCheck = uint32(lastCheck.Unix())
+	}
+	return e
+}
+
+// loadOpenTargets to get the targets with an open action from db, before the
+// first write. The writes and checks keep them after.
+func loadOpenTargets() error {
+	targets, _, _, err := db.GetActions()
+	if err != nil {
+		return err
+	}
+	writeMu.Lock()
+	openTargets = targetSet(targets)
+	writeMu.Unlock()
+	return nil
+}
+
+func targetSet(targets []string) map[string]bool {
+	set := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		set[t] = true
+	}
+	return set
+}
+
+// expiredTargets to count the targets of open no longer in after.
+func expiredTargets(open map[string]bool, after []string) uint32 {
+	active := targetSet(after)
+
+	var n uint32
+	for t := range open {
+		if !active[t] {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckInterval(t *testing.T) {
+	defer setCheckInterval(DefaultCheckInterval)
+
+	if err := setCheckInterval(500 * time.Millisecond); err != ErrCheckInterval {
+		t.Errorf("expect ErrCheckInterval, got %v", err)
+	}
+	if err := setCheckInterval(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if d := getCheckInterval(); d != 10*time.Second {
+		t.Errorf("expect 10s, got %s", d)
+	}
+	select {
+	case <-intervalChan:
+	default:
+		t.Error("checkActions should be told")
+	}
+
+	at := time.Unix(1500000000, 0)
+	recordCheck(at, 2, 3*time.Millisecond)
+	e := expiryStats()
+	if e.Interval != 10 || e.LastCheck != 1500000000 || e.LastExpired != 2 || e.LastDuration != 3 || e.Checks == 0 {
+		t.Errorf("wrong expiry %v", e)
+	}
+
+	if n := expiredTargets(targetSet([]string{"a", "b", "c"}), []string{"b", "d"}); n != 2 {
+		t.Errorf("expect 2 expired, got %d", n)
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/tracerun/tracerun/lg"
+	"go.uber.org/zap"
+)
+
+// Frame versions. A connection starts with Version1 and switches to the
+// version agreed by the hello route.
+const (
+	// Version1 header: uint16 data length, uint8 route.
+	Version1 = uint8(1)
+	// Version2 header: uint16 data length, uint8 route, uint32 request ID.
+	// Replies echo the request ID of the request.
+	Version2 = uint8(2)
+	// Version3 header: uint32 data length, uint8 route, uint32 request ID.
+	// Replies are limited by MaxFrameBytes instead of 64 KiB, requests by
+	// MaxRequestBytes.
+	Version3 = uint8(3)
+
+	// MaxVersion the highest frame version supported
+	MaxVersion = Version3
+
+	// MaxFrameBytes the largest data of a Version3 frame
+	MaxFrameBytes = 64 << 20
+	// MaxRequestBytes the largest data of a frame read by the service.
+	// Requests are small, only replies use the length of Version3.
+	MaxRequestBytes = 64 << 10
+)
+
+// ErrFrameTooLarge the data doesn't fit in a frame
+var ErrFrameTooLarge = errors.New("frame too large")
+
+// HeaderBytes to get the header length of a frame version.
+func HeaderBytes(version uint8) int {
+	switch version {
+	case Version2:
+		return headerBytes + 4
+	case Version3:
+		return headerBytes + 6
+	default:
+		return headerBytes
+	}
+}
+
+// MaxDataBytes to get the largest data of a frame version.
+func MaxDataBytes(version uint8) int {
+	if version >= Version3 {
+		return MaxFrameBytes
+	}
+	return math.MaxUint16
+}
+
+// GenerateFrameHeader to generate a header of a frame version. The request ID
+// is ignored by Version1, length must not exceed MaxDataBytes.
+func GenerateFrameHeader(version uint8, length uint32, route uint8, id uint32) []byte {
+	buf := make([]byte, HeaderBytes(version))
+	switch version {
+	case Version1:
+		binary.LittleEndian.PutUint16(buf, uint16(length))
+		buf[2] = byte(route)
+	case Version2:
+		binary.LittleEndian.PutUint16(buf, uint16(length))
+		buf[2] = byte(route)
+		binary.LittleEndian.PutUint32(buf[3:], id)
+	default:
+		binary.LittleEndian.PutUint32(buf, length)
+		buf[4] = byte(route)
+		binary.LittleEndian.PutUint32(buf[5:], id)
+	}
+	return buf
+}
+
+// ReadFrame to read one frame of a version, the request ID is 0 for Version1.
+func ReadFrame(r io.Reader, version uint8) ([]byte, uint8, uint32, error) {
+	return readFrameLimit(r, version, MaxFrameBytes)
+}
+
+// readFrameLimit to read one frame with data of at most limit bytes. The data
+// of a larger frame is not read, nor allocated.
+func readFrameLimit(r io.Reader, version uint8, limit uint32) ([]byte, uint8, uint32, error) {
+	headerBuf := make([]byte, HeaderBytes(version))
+	if _, err := io.ReadFull(r, headerBuf); err != nil {
+		return nil, 0, 0, err
+	}
+
+	var byteCount, id uint32
+	var route uint8
+	switch version {
+	case Version1:
+		byteCount = uint32(binary.LittleEndian.Uint16(headerBuf))
+		route = uint8(headerBuf[2])
+	case Version2:
+		byteCount = uint32(binary.LittleEndian.Uint16(headerBuf))
+		route = uint8(headerBuf[2])
+		id = binary.LittleEndian.Uint32(headerBuf[3:])
+	default:
+		byteCount = binary.LittleEndian.Uint32(headerBuf)
+		route = uint8(headerBuf[4])
+		id = binary.LittleEndian.Uint32(headerBuf[5:])
+	}
+	if byteCount > limit {
+		return nil, route, id, ErrFrameTooLarge
+	}
+
+	buf := make([]byte, byteCount)
+	n, err := io.ReadFull(r, buf)
+	if err != nil {
+		return nil, route, id, err
+	}
+	if n != int(byteCount) {
+		return nil, route, id, ErrDataLength
+	}
+
+	return buf, route, id, nil
+}
+
+// session the frame state of a connection, handed to the routes as writer.
+type session struct {
+	io.Writer
+	version uint8
+	// requestID of the request in hand
+	requestID uint32
+	// perm the routes the session can call, see routePerm
+	perm int
+	// user and host of the client authenticated, the user is empty for the
+	// token of the token file
+	user string
+	host string
+	// remote address of the client, to audit
+	remote string
+	// local a peer of the Unix socket running as the service user
+	local bool
+}
+
+func newSession(w io.Writer) *session {
+	return &session{
+		Writer:  w,
+		version: Version1,
+		perm:    defaultPerm(),
+	}
+}
+
+// readFrame to read a request, larger than MaxRequestBytes it's an error.
+func (s *session) readFrame(r io.Reader) ([]byte, uint8, error) {
+	data, route, id, err := readFrameLimit(r, s.version, MaxRequestBytes)
+	s.requestID = id
+	return data, route, err
+}
+
+func (s *session) writeFrame(route uint8, buf []byte) error {
+	return writeVersionFrame(s.Writer, s.version, route, s.requestID, buf)
+}
+
+// writeFrame to write a reply frame. Sessions write in their version with the
+// request ID, other writers get a Version1 frame.
+func writeFrame(w io.Writer, route uint8, buf []byte) error {
+	if s, ok := w.(*session); ok {
+		return s.writeFrame(route, buf)
+	}
+	return writeVersionFrame(w, Version1, route, 0, buf)
+}
+
+// writeVersionFrame to write a frame. Data too large for the version is
+// replaced by an ErrorMessage, so the stream never gets a truncated length.
+func writeVersionFrame(w io.Writer, version, route uint8, id uint32, buf []byte) error {
+	if len(buf) > MaxDataBytes(version) {
+		lg.L.Warn("reply too large", zap.Uint8("version", version), zap.Uint8("route", route), zap.Int("bytes", len(buf)))
+		errMsg := fmt.Sprintf("reply of %d bytes exceeds the limit of frame version %d", len(buf), version)
+		if version < MaxVersion {
+			errMsg += fmt.Sprintf(", use version %d", MaxVersion)
+		}
+		buf, _ = proto.Marshal(&ErrorMessage{Message: errMsg})
+		route = uint8(255)
+		if err := writeVersionFrame(w, version, route, id, buf); err != nil {
+			return err
+		}
+		return ErrFrameTooLarge
+	}
+
+	headerBuf := GenerateFrameHeader(version, uint32(len(buf)), route, id)
+	_, err := w.Write(append(headerBuf, buf...))
+	return err
+}
+
+// writeMessage to write a message as the reply of a route.
+func writeMessage(w io.Writer, route uint8, msg proto.Message) {
+	buf, err := proto.Marshal(msg)
+	if err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+
+	if err := writeFrame(w, route, buf); err != nil {
+		lg.L.Error("error writing", zap.Error(err))
+	}
+}
+
+// negotiate to agree a frame version with the version asked by the client.
+func negotiate(asked uint32) (uint8, error) {
+	if asked < uint32(Version1) {
+		return 0, fmt.Errorf("unknown frame version %d", asked)
+	}
+	if asked > uint32(MaxVersion) {
+		return MaxVersion, nil
+	}
+	return uint8(asked), nil
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/tracerun/tracerun/lg"
+)
+
+func TestFrameVersions(t *testing.T) {
+	for _, version := range []uint8{Version1, Version2, Version3} {
+		var buf bytes.Buffer
+		buf.Write(GenerateFrameHeader(version, 3, 21, 42))
+		buf.WriteString("abc")
+		if buf.Len() != HeaderBytes(version)+3 {
+			t.Errorf("version %d: wrong frame length %d", version, buf.Len())
+		}
+
+		data, route, id, err := ReadFrame(&buf, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectID := uint32(42)
+		if version == Version1 {
+			expectID = 0
+		}
+		if string(data) != "abc" || route != 21 || id != expectID {
+			t.Errorf("version %d: wrong frame %q %d %d", version, data, route, id)
+		}
+	}
+}
+
+func TestHelloSession(t *testing.T) {
+	lg.InitLogger(false, true, "")
+	initQueue(bufferCount, PolicyBlock)
+
+	var out bytes.Buffer
+	sess := newSession(&out)
+
+	// the hello reply is still in Version1
+	hello(mustMarshal(&Hello{Version: 99}), sess)
+	data, route, err := ReadOne(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h Hello
+	if err := proto.Unmarshal(data, &h); err != nil {
+		t.Fatal(err)
+	}
+	if route != 4 || h.Version != uint32(MaxVersion) || sess.version != MaxVersion {
+		t.Fatalf("wrong hello %d %v %d", route, h, sess.version)
+	}
+
+	// replies echo the request ID
+	var in bytes.Buffer
+	in.Write(GenerateFrameHeader(sess.version, 0, 3, 7))
+	in.Write(GenerateFrameHeader(sess.version, 0, 3, 8))
+	for _, expect := range []uint32{7, 8} {
+		data, _, err := sess.readFrame(&in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		getStats(data, sess)
+		if _, route, id, err := ReadFrame(&out, sess.version); err != nil || route != 3 || id != expect {
+			t.Errorf("expect reply %d, got %d %d %v", expect, route, id, err)
+		}
+	}
+
+	// errors too
+	sess.requestID = 9
+	WriteErrorMessage(ErrBusy, sess)
+	if _, route, id, err := ReadFrame(&out, sess.version); err != nil || route != 255 || id != 9 {
+		t.Errorf("expect error reply 9, got %d %d %v", route, id, err)
+	}
+
+	// a request larger than MaxRequestBytes is refused from its header
+	in.Reset()
+	in.Write(GenerateFrameHeader(sess.version, MaxFrameBytes, 20, 10))
+	if _, _, err := sess.readFrame(&in); err != ErrFrameTooLarge {
+		t.Errorf("expect ErrFrameTooLarge, got %v", err)
+	}
+
+	hello(mustMarshal(&Hello{}), newSession(&out))
+	if _, route, _ := ReadOne(&out); route != 255 {
+		t.Errorf("version 0 should be refused, got route %d", route)
+	}
+}
+
+func largeTargets() *Targets {
+	var all Targets
+	for i := 0; i < 10000; i++ {
+		all.Target = append(all.Target, fmt.Sprintf("project/repo/some/longer/path/file%05d.go", i))
+	}
+	return &all
+}
+
+func TestLargeReply(t *testing.T) {
+	lg.InitLogger(false, true, "")
+	all := largeTargets()
+
+	var out bytes.Buffer
+	sess := newSession(&out)
+	sess.version = Version3
+	sess.requestID = 5
+	writeMessage(&out, 20, all)
+	writeMessage(sess, 20, all)
+
+	// Version1 writer gets an error instead of a truncated frame
+	data, route, err := ReadOne(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errMsg ErrorMessage
+	if err := proto.Unmarshal(data, &errMsg); err != nil || route != 255 {
+		t.Fatalf("expect error reply, got route %d %v", route, err)
+	}
+
+	data, route, id, err := ReadFrame(&out, Version3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) <= math.MaxUint16 {
+		t.Fatalf("reply should be larger than 65535 bytes, got %d", len(data))
+	}
+	var got Targets
+	if err := proto.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if route != 20 || id != 5 || len(got.Target) != len(all.Target) || got.Target[9999] != all.Target[9999] {
+		t.Errorf("wrong large reply, route %d id %d targets %d", route, id, len(got.Target))
+	}
+	if out.Len() != 0 {
+		t.Errorf("%d bytes left in the stream", out.Len())
+	}
+}
+
+func TestLargeTCPReply(t *testing.T) {
+	lg.InitLogger(false, true, "")
+	all := largeTargets()
+
+	router := map[uint8]RouteFunc{
+		4:  hello,
+		20: func(b []byte, w io.Writer) { writeMessage(w, 20, all) },
+	}
+	s := NewTCPServer(8872, router)
+	go s.Start()
+	defer s.Shutdown(time.Second)
+
+	var conn net.Conn
+	var err error
+	for i := 0; i < 50; i++ {
+		if conn, err = net.Dial("tcp", "127.0.0.1:8872"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	buf := mustMarshal(&Hello{Version: uint32(Version3)})
+	conn.Write(append(GenerateHeaderBuf(uint16(len(buf)), 4), buf...))
+	if _, route, err := ReadOne(conn); err != nil || route != 4 {
+		t.Fatalf("hello failed, route %d %v", route, err)
+	}
+
+	conn.Write(GenerateFrameHeader(Version3, 0, 20, 1))
+	data, route, id, err := ReadFrame(conn, Version3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Targets
+	if err := proto.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if route != 20 || id != 1 || len(data) <= math.MaxUint16 || len(got.Target) != len(all.Target) {
+		t.Errorf("wrong large reply, route %d id %d bytes %d", route, id, len(data))
+	}
+}
+
+func mustMarshal(msg proto.Message) []byte {
+	buf, err := proto.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+	return buf
+}
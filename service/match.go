@@ -0,0 +1,143 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+)
+
+// matcher to select targets by a glob and a regular expression.
+type matcher struct {
+	glob  *regexp.Regexp
+	regex *regexp.Regexp
+}
+
+// newMatcher to match targets by glob and by regex, either can be empty. A
+// nil matcher is returned if both are empty.
+func newMatcher(glob, regex string) (*matcher, error) {
+	if len(glob) == 0 && len(regex) == 0 {
+		return nil, nil
+	}
+
+	var m matcher
+	if len(glob) != 0 {
+		expr, err := globToRegexp(glob)
+		if err != nil {
+			return nil, err
+		}
+		if m.glob, err = regexp.Compile(expr); err != nil {
+			return nil, err
+		}
+	}
+	if len(regex) != 0 {
+		var err error
+		if m.regex, err = regexp.Compile(regex); err != nil {
+			return nil, err
+		}
+	}
+	return &m, nil
+}
+
+// filterMatcher to get the matcher of a filter, nil if the filter is empty.
+func filterMatcher(f *Filter) (*matcher, error) {
+	if f == nil {
+		return nil, nil
+	}
+	return newMatcher(f.Glob, f.Regex)
+}
+
+// match a target, a nil matcher matches all.
+func (m *matcher) match(target string) bool {
+	if m == nil {
+		return true
+	}
+	if m.glob != nil && !m.glob.MatchString(target) {
+		return false
+	}
+	if m.regex != nil && !m.regex.MatchString(target) {
+		return false
+	}
+	return true
+}
+
+// filter to keep the matched targets.
+func (m *matcher) filter(targets []string) []string {
+	if m == nil {
+		return targets
+	}
+	var matched []string
+	for _, target := range targets {
+		if m.match(target) {
+			matched = append(matched, target)
+		}
+	}
+	return matched
+}
+
+// globToRegexp to convert a glob to an anchored regular expression. "*" and
+// "?" don't match a one character separator, "**" matches anything, "[...]"
+// is a class and "\" escapes the next character.
+func globToRegexp(glob string) (string, error) {
+	sep := regexp.QuoteMeta(separator)
+	// one character in a level
+	char := "."
+	if utf8.RuneCountInString(separator) == 1 {
+		char = "[^" + sep + "]"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				// "**/" also matches no level at all
+				if i+len(separator) < len(glob) && glob[i+1:i+1+len(separator)] == separator {
+					i += len(separator)
+					buf.WriteString("(?:.*" + sep + ")?")
+				} else {
+					buf.WriteString(".*")
+				}
+			} else {
+				buf.WriteString(char + "*")
+			}
+		case '?':
+			buf.WriteString(char)
+		case '[':
+			end := i + 1
+			if end < len(glob) && (glob[end] == '!' || glob[end] == '^') {
+				end++
+			}
+			if end < len(glob) && glob[end] == ']' {
+				end++
+			}
+			for end < len(glob) && glob[end] != ']' {
+				end++
+			}
+			if end >= len(glob) {
+				return "", fmt.Errorf("unclosed [ in glob %q", glob)
+			}
+			class := glob[i+1 : end]
+			if len(class) != 0 && class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			buf.WriteString("[" + class + "]")
+			i = end
+		case '\\':
+			if i+1 >= len(glob) {
+				return "", fmt.Errorf("trailing \\ in glob %q", glob)
+			}
+			i++
+			buf.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			buf.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+
+	buf.WriteString("$")
+	return buf.String(), nil
+}
@@ -0,0 +1,60 @@
+package service
+
+import "testing"
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		glob   string
+		target string
+		expect bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "service/tcp.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "service/tcp.go", true},
+		{"service/**", "service/a/b.go", true},
+		{"**/node_modules/**", "web/node_modules/x/index.js", true},
+		{"**/node_modules/**", "web/node_modules_old/index.js", false},
+		{"file?.go", "file1.go", true},
+		{"file?.go", "file/.go", false},
+		{"file[0-9].go", "file7.go", true},
+		{"file[!0-9].go", "file7.go", false},
+		{"a\\*b", "a*b", true},
+		{"a\\*b", "axb", false},
+		{"a.b", "axb", false},
+	}
+
+	for _, test := range tests {
+		m, err := newMatcher(test.glob, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.match(test.target); got != test.expect {
+			t.Errorf("%s on %s: expect %v, got %v", test.glob, test.target, test.expect, got)
+		}
+	}
+
+	if _, err := newMatcher("[abc", ""); err == nil {
+		t.Error("unclosed class should fail")
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	m, err := newMatcher("", "")
+	if err != nil || m != nil || !m.match("any") {
+		t.Error("empty matcher should match all")
+	}
+
+	m, err = newMatcher("**/*.go", "_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := m.filter([]string{"a.go", "a_test.go", "b/c_test.go", "b/c_test.txt"})
+	if len(targets) != 2 || targets[0] != "a_test.go" || targets[1] != "b/c_test.go" {
+		t.Errorf("wrong targets %v", targets)
+	}
+
+	if _, err := newMatcher("", "("); err == nil {
+		t.Error("wrong regex should fail")
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+var (
+	// metaMu guards metaFile
+	metaMu sync.Mutex
+	// metaFile the metadata of the actions are appended to, nil if closed
+	metaFile *os.File
+)
+
+// metaRecord a line of the metadata file, the metadata of an action stored
+// in db. db only stores targets and times, so the metadata is kept aside.
+type metaRecord struct {
+	Target string            `json:"target"`
+	Ts     uint32            `json:"ts"`
+	Meta   map[string]string `json:"meta"`
+}
+
+// openMetaFile to append the metadata of the actions to path, created if
+// missing.
+func openMetaFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	metaMu.Lock()
+	metaFile = f
+	metaMu.Unlock()
+	return nil
+}
+
+// closeMetaFile to close the metadata file, nothing is stored after.
+func closeMetaFile() error {
+	metaMu.Lock()
+	defer metaMu.Unlock()
+	if metaFile == nil {
+		return nil
+	}
+	err := metaFile.Close()
+	metaFile = nil
+	return err
+}
+
+// storeMeta to append the metadata of an action written to db, as a JSON
+// line. Nothing is stored for an action without metadata.
+func storeMeta(a *act) error {
+	if len(a.meta) == 0 {
+		return nil
+	}
+	buf, err := json.Marshal(&metaRecord{Target: a.target, Ts: a.ts, Meta: a.meta})
+	if err != nil {
+		return err
+	}
+
+	metaMu.Lock()
+	defer metaMu.Unlock()
+	if metaFile == nil {
+		return nil
+	}
+	_, err = metaFile.Write(append(buf, '\n'))
+	return err
+}
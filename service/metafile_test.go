@@ -0,0 +1,47 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreMeta(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.meta")
+	if err := openMetaFile(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range []*act{
+		{target: "a", ts: 1500000000, meta: map[string]string{"editor": "vim"}},
+		{target: "b", ts: 1500000001},
+		{target: "@alice:c", ts: 1500000002, meta: map[string]string{"user": "alice", "host": "laptop"}},
+	} {
+		if err := storeMeta(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := closeMetaFile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := storeMeta(&act{target: "d", meta: map[string]string{"x": "y"}}); err != nil {
+		t.Errorf("storing after close should be skipped, got %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expect 2 lines, got %q", lines)
+	}
+	var r metaRecord
+	if err := json.Unmarshal([]byte(lines[1]), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Target != "@alice:c" || r.Ts != 1500000002 || r.Meta["host"] != "laptop" {
+		t.Errorf("wrong record %v", r)
+	}
+}
@@ -1,7 +1,10 @@
 package service
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
@@ -10,26 +13,44 @@ import (
 )
 
 const (
-	bufferCount   = 200
-	tickerSeconds = 60
+	// bufferCount default size of the action queue
+	bufferCount = 200
+	// DefaultCheckInterval between two checks of expired actions
+	DefaultCheckInterval = 60 * time.Second
 )
 
 var (
-	// actionChan is used to handle actions
-	actionChan = make(chan *act, bufferCount)
+	// ErrEmptyTarget an action without target
+	ErrEmptyTarget = errors.New("empty target")
+
+	// maxClockSkew how far a client timestamp can be in the future
+	maxClockSkew = 5 * time.Minute
+	// maxBackdate how far a client timestamp can be in the past
+	maxBackdate = 7 * 24 * time.Hour
+
+	// quitChan is closed to stop receiveActions and checkActions
+	quitChan = make(chan struct{})
+	// workers counts the running receiveActions and checkActions
+	workers sync.WaitGroup
+	// flushChan asks receiveActions to write the queued actions now, the
+	// count written is replied
+	flushChan = make(chan chan int)
 )
 
-type act struct {
-	target string
-	ts     uint32
-}
-
 func receiveActions() {
+	defer workers.Done()
 	for {
-		a := <-actionChan
-		addOneAction(a)
+		select {
+		case a := <-actionChan:
+			addOneAction(a)
+		case done := <-flushChan:
+			done <- flushQueue(addOneAction)
+			continue
+		case <-quitChan:
+			return
+		}
 	Remaining:
-		for i := 0; i < bufferCount-1; i++ {
+		for i := 0; i < cap(actionChan)-1; i++ {
 			select {
 			case a := <-actionChan:
 				addOneAction(a)
@@ -40,35 +61,131 @@ func receiveActions() {
 	}
 }
 
+// drainActions to write every action left in actionChan. Must be called after
+// receiveActions returned and no more actions are enqueued.
+func drainActions() int {
+	count := 0
+	for {
+		select {
+		case a := <-actionChan:
+			addOneAction(a)
+			count++
+		default:
+			return count
+		}
+	}
+}
+
+// flushQueue to write the actions queued now, not the ones coming meanwhile.
+func flushQueue(write func(*act)) int {
+	count := 0
+	for n := len(actionChan); count < n; count++ {
+		select {
+		case a := <-actionChan:
+			write(a)
+		default:
+			// dropped by PolicyDropOldest meanwhile
+			return count
+		}
+	}
+	return count
+}
+
 func addOneAction(a *act) {
-	lg.L.Debug("action from Q", zap.Any("target", a.target), zap.Uint32("ts", a.ts))
-	if err := db.AddAction(a.target, a.ts); err != nil {
+	lg.L.Debug("action from Q", zap.Any("target", a.target), zap.Uint32("ts", a.ts), zap.Any("meta", a.meta))
+	start := time.Now()
+	writeMu.Lock()
+	err := db.AddAction(a.target, a.ts)
+	if err == nil {
+		openTargets[a.target] = true
+	}
+	writeMu.Unlock()
+	recordAdd(time.Since(start), err)
+	if err != nil {
 		lg.L.Error("error add action", zap.Error(err))
+	} else if err := storeMeta(a); err != nil {
+		lg.L.Error("error store action metadata", zap.Error(err))
 	}
+	a.finish(err)
 }
 
 func checkActions() {
-	for _ = range time.Tick(tickerSeconds * time.Second) {
-		if err := db.CheckExpirations(); err != nil {
-			lg.L.Error("error while checking actions", zap.Error(err))
+	defer workers.Done()
+	ticker := time.NewTicker(getCheckInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			checkExpirations()
+		case <-intervalChan:
+			ticker.Reset(getCheckInterval())
+		case <-quitChan:
+			return
 		}
 	}
 }
 
-// exit uint8(0) to stop the server
+// checkExpirations to close the expired actions, the number of actions
+// closed is recorded. No action is written meanwhile, so the actions open
+// before are the ones known by the writes and db is read once, after.
+func checkExpirations() {
+	start := time.Now()
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
+	if err := db.CheckExpirations(); err != nil {
+		lg.L.Error("error while checking actions", zap.Error(err))
+		return
+	}
+	after, _, _, err := db.GetActions()
+	if err != nil {
+		lg.L.Error("error while checking actions", zap.Error(err))
+		return
+	}
+
+	expired := expiredTargets(openTargets, after)
+	openTargets = targetSet(after)
+	recordCheck(start, expired, time.Since(start))
+	lg.L.Debug("actions checked", zap.Uint32("expired", expired))
+}
+
+// exit uint8(0) to stop the server, for the sessions allowed to call the
+// admin route.
 func exit(b []byte, w io.Writer) {
-	stopChan <- true
+	if err := checkExit(w); err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+	requestStop()
 }
 
 // ping uint8(1) used to extend readtimeout
 func ping(b []byte, w io.Writer) {}
 
-// getMeta uint8(2) to get meta information
+// getMeta uint8(2) to get meta information. A Hello in the request agrees
+// the frame version too, like route 4: daemons without hello reply meta
+// ignoring it, so a client learns the version without waiting for a reply.
 func getMeta(b []byte, w io.Writer) {
 	thisRoute := uint8(2)
 
 	var meta Meta
 
+	var version uint8
+	if len(b) != 0 {
+		var h Hello
+		if err := proto.Unmarshal(b, &h); err != nil {
+			WriteErrorMessage(err, w)
+			return
+		}
+		var err error
+		if version, err = negotiate(h.Version); err != nil {
+			WriteErrorMessage(err, w)
+			return
+		}
+		meta.FrameVersion = uint32(version)
+	}
+
 	v, err := db.Version()
 	if err != nil {
 		WriteErrorMessage(err, w)
@@ -125,33 +242,326 @@ func getMeta(b []byte, w io.Writer) {
 	}
 	meta.ZoneOffset = offset
 
-	buf, err := proto.Marshal(&meta)
+	s, ok := w.(*session)
+	if ok {
+		meta.CallerUser, meta.CallerHost = s.user, s.host
+	}
+
+	writeMessage(w, thisRoute, &meta)
+	if ok && version != 0 {
+		s.version = version
+	}
+}
+
+// getStats uint8(3) to get the counters of the action queue
+func getStats(b []byte, w io.Writer) {
+	thisRoute := uint8(3)
+
+	writeMessage(w, thisRoute, queueStats())
+}
+
+// hello uint8(4) to agree a frame version. The reply is in the current
+// version, the following frames in both directions use the agreed one.
+func hello(b []byte, w io.Writer) {
+	thisRoute := uint8(4)
+
+	var h Hello
+	if err := proto.Unmarshal(b, &h); err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+	version, err := negotiate(h.Version)
 	if err != nil {
 		WriteErrorMessage(err, w)
 		return
 	}
 
-	headerBuf := GenerateHeaderBuf(uint16(len(buf)), thisRoute)
-	if _, err := w.Write(append(headerBuf, buf...)); err != nil {
-		lg.L.Error("error writing", zap.Error(err))
+	writeMessage(w, thisRoute, &Hello{Version: uint32(version)})
+	if s, ok := w.(*session); ok {
+		s.version = version
 	}
 }
 
 // action uint8(10) to receive action income.
 func action(b []byte, w io.Writer) {
-	// enqueue
-	go func() {
-		actionChan <- &act{
-			target: string(b),
-			ts:     uint32(time.Now().Unix()),
+	target, err := ingestTarget(string(b))
+	if err != nil {
+		lg.L.Debug("action rejected", zap.String("target", string(b)), zap.Error(err))
+		return
+	}
+	a := &act{
+		target: target,
+		ts:     uint32(time.Now().Unix()),
+	}
+	tagAct(w, a)
+	if err := enqueue(a); err == ErrBusy {
+		WriteErrorMessage(err, w)
+	}
+}
+
+// getRules uint8(5) to list the rules, reloaded from the rules file first if
+// asked.
+func getRules(b []byte, w io.Writer) {
+	thisRoute := uint8(5)
+
+	var req RulesRequest
+	if err := proto.Unmarshal(b, &req); err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+	if req.Reload {
+		if err := loadRules(); err != nil {
+			lg.L.Error("error reloading rules", zap.Error(err))
+			WriteErrorMessage(err, w)
+			return
+		}
+	}
+
+	writeMessage(w, thisRoute, listRules())
+}
+
+// expiry uint8(6) to get the interval and the last check of expired
+// actions, a non zero interval in seconds is set first.
+func expiry(b []byte, w io.Writer) {
+	thisRoute := uint8(6)
+
+	var req ExpiryRequest
+	if err := proto.Unmarshal(b, &req); err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+	if req.Interval != 0 {
+		if err := setCheckInterval(time.Duration(req.Interval) * time.Second); err != nil {
+			WriteErrorMessage(err, w)
+			return
 		}
-	}()
+		lg.L.Info("check interval changed", zap.Uint32("seconds", req.Interval))
+	}
+
+	writeMessage(w, thisRoute, expiryStats())
+}
+
+// reload uint8(7) to reload the config like on SIGHUP.
+func reload(b []byte, w io.Writer) {
+	thisRoute := uint8(7)
+
+	result, err := reloadConfig()
+	if err != nil {
+		lg.L.Error("error reloading config", zap.Error(err))
+		WriteErrorMessage(err, w)
+		return
+	}
+
+	writeMessage(w, thisRoute, result)
+}
+
+// auth uint8(8) to authenticate the session by a token, the routes of the
+// permission granted can be called after.
+func auth(b []byte, w io.Writer) {
+	thisRoute := uint8(8)
+
+	var req AuthRequest
+	if err := proto.Unmarshal(b, &req); err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+	acc, err := authenticate(req.Token)
+	if err != nil {
+		lg.L.Warn("authentication failed", zap.String("host", req.Host), zap.Error(err))
+		WriteErrorMessage(err, w)
+		return
+	}
+	if s, ok := w.(*session); ok {
+		s.perm, s.user, s.host = acc.perm, acc.user, req.Host
+	}
+	lg.L.Debug("authenticated", zap.String("user", acc.user), zap.String("host", req.Host))
+
+	writeMessage(w, thisRoute, &AuthResult{
+		Permission: uint32(acc.perm),
+		User:       acc.user,
+		Host:       req.Host,
+	})
 }
 
-// getActions uint8(11) to get all actions
+// admin uint8(9) to control the service, for an admin token or a local
+// peer of the Unix socket only.
+func admin(b []byte, w io.Writer) {
+	thisRoute := uint8(9)
+
+	var req AdminRequest
+	if err := proto.Unmarshal(b, &req); err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+	if !controlAllowed(w) {
+		lg.L.Warn("admin operation refused", append(callerFields(w), zap.String("op", req.Op))...)
+		WriteErrorMessage(ErrControl, w)
+		return
+	}
+	lg.L.Info("admin operation", append(callerFields(w), zap.String("op", req.Op))...)
+
+	result, err := control(&req)
+	if err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+	writeMessage(w, thisRoute, result)
+
+	if req.Op == AdminShutdown {
+		requestStop()
+	}
+}
+
+// timedAction uint8(12) to receive an action with the time it happened.
+func timedAction(b []byte, w io.Writer) {
+	var ta TimedAction
+	if err := proto.Unmarshal(b, &ta); err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+
+	a, err := newTimedAct(&ta, time.Now())
+	if err != nil {
+		lg.L.Debug("action rejected", zap.String("target", ta.Target), zap.Error(err))
+		WriteErrorMessage(err, w)
+		return
+	}
+	tagAct(w, a)
+	if err := enqueue(a); err == ErrBusy {
+		WriteErrorMessage(err, w)
+	}
+}
+
+// ackAction uint8(14) to receive an action and reply after it is written.
+// The reply is an Ack, or an ErrorMessage if the action is not written.
+func ackAction(b []byte, w io.Writer) {
+	thisRoute := uint8(14)
+
+	var ta TimedAction
+	if err := proto.Unmarshal(b, &ta); err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+
+	a, err := newTimedAct(&ta, time.Now())
+	if err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+	tagAct(w, a)
+	a.done = make(chan error, 1)
+	if err := enqueue(a); err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+	if err := <-a.done; err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+
+	writeMessage(w, thisRoute, &Ack{Ts: a.ts})
+}
+
+// actionBatch uint8(13) to receive actions in one message. Valid actions are
+// enqueued together in order, the reply tells which one is accepted. With ack
+// the reply is sent after the actions are written, with the write errors.
+func actionBatch(b []byte, w io.Writer) {
+	thisRoute := uint8(13)
+
+	var batch ActionBatch
+	if err := proto.Unmarshal(b, &batch); err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+
+	now := time.Now()
+	result := BatchResult{
+		Items: make([]*BatchResult_Item, len(batch.Actions)),
+	}
+	var acts []*act
+	accepted := make(map[*act]*BatchResult_Item)
+	for i, ta := range batch.Actions {
+		a, err := newTimedAct(ta, now)
+		if err != nil {
+			result.Items[i] = &BatchResult_Item{Error: err.Error()}
+			continue
+		}
+		tagAct(w, a)
+		if batch.Ack {
+			a.done = make(chan error, 1)
+		}
+		result.Items[i] = &BatchResult_Item{Accepted: true}
+		accepted[a] = result.Items[i]
+		acts = append(acts, a)
+	}
+
+	if err := enqueue(acts...); err != nil {
+		for _, item := range accepted {
+			item.Accepted = false
+			item.Error = err.Error()
+		}
+	} else if batch.Ack {
+		for _, a := range acts {
+			if err := <-a.done; err != nil {
+				accepted[a].Accepted = false
+				accepted[a].Error = err.Error()
+			}
+		}
+	}
+	for _, item := range result.Items {
+		if item.Accepted {
+			result.Accepted++
+		} else {
+			result.Rejected++
+		}
+	}
+
+	writeMessage(w, thisRoute, &result)
+}
+
+// newTimedAct to validate a client action against now.
+func newTimedAct(ta *TimedAction, now time.Time) (*act, error) {
+	if len(ta.Target) == 0 {
+		return nil, ErrEmptyTarget
+	}
+
+	ts := ta.Ts
+	if ts == 0 {
+		ts = uint32(now.Unix())
+	}
+	settingsMu.RLock()
+	skew, backdate := maxClockSkew, maxBackdate
+	settingsMu.RUnlock()
+
+	t := time.Unix(int64(ts), 0)
+	if t.After(now.Add(skew)) {
+		return nil, fmt.Errorf("timestamp %d is %s ahead of the server clock", ts, t.Sub(now))
+	}
+	if t.Before(now.Add(-backdate)) {
+		return nil, fmt.Errorf("timestamp %d is older than %s", ts, backdate)
+	}
+	target, err := ingestTarget(ta.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	return &act{
+		target: target,
+		ts:     ts,
+		meta:   ta.Metadata,
+	}, nil
+}
+
+// getActions uint8(11) to get all actions, or the ones matched by a Filter
 func getActions(b []byte, w io.Writer) {
 	thisRoute := uint8(11)
 
+	m, err := readFilter(b)
+	if err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+
 	var all AllActions
 	targets, starts, lasts, err := db.GetActions()
 	if err != nil {
@@ -160,86 +570,135 @@ func getActions(b []byte, w io.Writer) {
 		return
 	}
 
+	prefix := scopeOf(w)
 	for i := 0; i < len(targets); i++ {
+		target, ok := unscope(prefix, targets[i])
+		if !ok || !m.match(target) {
+			continue
+		}
 		all.Actions = append(all.Actions, &AllActions_Act{
-			Target: targets[i],
+			Target: target,
 			Start:  starts[i],
 			Last:   lasts[i],
 		})
 	}
 
-	buf, err := proto.Marshal(&all)
-	if err != nil {
-		WriteErrorMessage(err, w)
-		return
-	}
-
-	headerBuf := GenerateHeaderBuf(uint16(len(buf)), thisRoute)
-	if _, err := w.Write(append(headerBuf, buf...)); err != nil {
-		lg.L.Error("error writing", zap.Error(err))
-	}
+	writeMessage(w, thisRoute, &all)
 }
 
-// getTargets uint8(20) to get all targets
+// getTargets uint8(20) to get all targets, or the ones matched by a Filter
 func getTargets(b []byte, w io.Writer) {
 	thisRoute := uint8(20)
 
-	targets := db.GetTargets()
+	m, err := readFilter(b)
+	if err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+
+	targets := m.filter(scopeTargets(scopeOf(w), db.GetTargets()))
 
 	var all Targets
 	all.Target = targets
 
-	buf, err := proto.Marshal(&all)
-	if err != nil {
+	writeMessage(w, thisRoute, &all)
+}
+
+// getSlots uint8(21) to get slots of a target in a range. With a limit the
+// slots are paged, the reply has the token of the next page, and db is only
+// queried for the windows of time the page needs.
+func getSlots(b []byte, w io.Writer) {
+	thisRoute := uint8(21)
+
+	var rang SlotRange
+	if err := proto.Unmarshal(b, &rang); err != nil {
 		WriteErrorMessage(err, w)
 		return
 	}
 
-	headerBuf := GenerateHeaderBuf(uint16(len(buf)), thisRoute)
-	if _, err := w.Write(append(headerBuf, buf...)); err != nil {
-		lg.L.Error("error writing", zap.Error(err))
+	all, err := querySlots(&rang, scopeOf(w))
+	if err != nil {
+		WriteErrorMessage(err, w)
+		return
 	}
+
+	writeMessage(w, thisRoute, all)
 }
 
-// getSlots uint8(21) to get slots of a target in a range
-func getSlots(b []byte, w io.Writer) {
-	thisRoute := uint8(21)
+// streamSlots uint8(22) to get slots of a target in a range as several
+// replies of at most limit slots, queried window by window. The last reply
+// has no next page token.
+func streamSlots(b []byte, w io.Writer) {
+	thisRoute := uint8(22)
 
 	var rang SlotRange
 	if err := proto.Unmarshal(b, &rang); err != nil {
 		WriteErrorMessage(err, w)
 		return
 	}
+	if rang.Limit == 0 {
+		rang.Limit = defaultChunkSlots
+	}
 
-	startsResult, slotsResult, err := db.GetSlots(rang.Target, rang.Start, rang.End)
+	p, err := newSlotPager(&rang, scopeOf(w))
 	if err != nil {
 		WriteErrorMessage(err, w)
 		return
 	}
-	var slots []*Slot
-	for i := 0; i < len(startsResult); i++ {
-		oneStarts, oneSlots := startsResult[i], slotsResult[i]
-		for j := 0; j < len(oneStarts); j++ {
-			slots = append(slots, &Slot{
-				Start: oneStarts[j],
-				Slot:  oneSlots[j],
-			})
-		}
+	if err := chunkSlots(p, rang.Limit, func(chunk *Slots) {
+		writeMessage(w, thisRoute, chunk)
+	}); err != nil {
+		WriteErrorMessage(err, w)
+	}
+}
+
+// getReport uint8(23) to get the time spent on targets by hour, day, week or
+// month.
+func getReport(b []byte, w io.Writer) {
+	thisRoute := uint8(23)
+
+	var req ReportRequest
+	if err := proto.Unmarshal(b, &req); err != nil {
+		WriteErrorMessage(err, w)
+		return
 	}
 
-	var all Slots
-	all.Slots = slots
+	report, err := buildReport(&req, scopeOf(w))
+	if err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+
+	writeMessage(w, thisRoute, report)
+}
+
+// getTargetTree uint8(24) to get the targets as a tree split by the
+// separator, with the time spent on each level.
+func getTargetTree(b []byte, w io.Writer) {
+	thisRoute := uint8(24)
 
-	buf, err := proto.Marshal(&all)
+	var req TreeRequest
+	if err := proto.Unmarshal(b, &req); err != nil {
+		WriteErrorMessage(err, w)
+		return
+	}
+
+	root, err := buildTree(&req, scopeOf(w))
 	if err != nil {
 		WriteErrorMessage(err, w)
 		return
 	}
 
-	headerBuf := GenerateHeaderBuf(uint16(len(buf)), thisRoute)
-	if _, err := w.Write(append(headerBuf, buf...)); err != nil {
-		lg.L.Error("error writing", zap.Error(err))
+	writeMessage(w, thisRoute, root)
+}
+
+// readFilter to read an optional Filter, no data matches all.
+func readFilter(b []byte) (*matcher, error) {
+	var f Filter
+	if err := proto.Unmarshal(b, &f); err != nil {
+		return nil, err
 	}
+	return filterMatcher(&f)
 }
 
 func getRouter() map[uint8]RouteFunc {
@@ -248,10 +707,23 @@ func getRouter() map[uint8]RouteFunc {
 	m[uint8(0)] = exit
 	m[uint8(1)] = ping
 	m[uint8(2)] = getMeta
+	m[uint8(3)] = getStats
+	m[uint8(4)] = hello
+	m[uint8(5)] = getRules
+	m[uint8(6)] = expiry
+	m[uint8(7)] = reload
+	m[uint8(8)] = auth
+	m[uint8(9)] = admin
 	m[uint8(10)] = action
 	m[uint8(11)] = getActions
+	m[uint8(12)] = timedAction
+	m[uint8(13)] = actionBatch
+	m[uint8(14)] = ackAction
 	m[uint8(20)] = getTargets
 	m[uint8(21)] = getSlots
+	m[uint8(22)] = streamSlots
+	m[uint8(23)] = getReport
+	m[uint8(24)] = getTargetTree
 
 	return m
 }
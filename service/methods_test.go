@@ -0,0 +1,66 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+func TestNewTimedAct(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+
+	tests := []struct {
+		ts uint32
+		ok bool
+	}{
+		{0, true},
+		{1500000000, true},
+		{uint32(now.Add(maxClockSkew).Unix()), true},
+		{uint32(now.Add(maxClockSkew + time.Second).Unix()), false},
+		{uint32(now.Add(-maxBackdate).Unix()), true},
+		{uint32(now.Add(-maxBackdate - time.Second).Unix()), false},
+	}
+
+	for _, test := range tests {
+		a, err := newTimedAct(&TimedAction{Target: "a", Ts: test.ts}, now)
+		if test.ok != (err == nil) {
+			t.Errorf("ts %d: unexpected error %v", test.ts, err)
+			continue
+		}
+		if test.ok && test.ts == 0 && a.ts != uint32(now.Unix()) {
+			t.Errorf("ts 0 should be now, got %d", a.ts)
+		}
+	}
+
+	if _, err := newTimedAct(&TimedAction{}, now); err != ErrEmptyTarget {
+		t.Errorf("expect %v, got %v", ErrEmptyTarget, err)
+	}
+}
+
+func TestTimedActionMetadata(t *testing.T) {
+	buf, err := proto.Marshal(&TimedAction{
+		Target:   "a",
+		Ts:       1500000000,
+		Metadata: map[string]string{"editor": "vim"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ta TimedAction
+	if err := proto.Unmarshal(buf, &ta); err != nil {
+		t.Fatal(err)
+	}
+	if ta.Target != "a" || ta.Ts != 1500000000 || ta.Metadata["editor"] != "vim" {
+		t.Errorf("wrong action %v", ta)
+	}
+
+	a, err := newTimedAct(&ta, time.Unix(1500000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.meta["editor"] != "vim" {
+		t.Errorf("expect the metadata kept, got %v", a.meta)
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/tracerun/tracerun/lg"
+	"go.uber.org/zap"
+)
+
+// addBuckets the upper bounds in seconds of the AddAction latency histogram
+var addBuckets = []float64{0.0001, 0.001, 0.01, 0.1, 1}
+
+var (
+	writtenCount   uint64
+	addErrorCount  uint64
+	addNanos       uint64
+	addBucketCount [6]uint64 // one more for +Inf
+
+	openConns   int64
+	routeCounts [256]uint64
+)
+
+// recordAdd to count an action written to the db in d.
+func recordAdd(d time.Duration, err error) {
+	if err != nil {
+		atomic.AddUint64(&addErrorCount, 1)
+	} else {
+		atomic.AddUint64(&writtenCount, 1)
+	}
+	atomic.AddUint64(&addNanos, uint64(d))
+
+	i := 0
+	for i < len(addBuckets) && d.Seconds() > addBuckets[i] {
+		i++
+	}
+	atomic.AddUint64(&addBucketCount[i], 1)
+}
+
+// MetricsServer to expose the metrics over HTTP in the Prometheus text
+// format, on /metrics.
+type MetricsServer struct {
+	addr string
+	ln   net.Listener
+	srv  *http.Server
+}
+
+// NewMetricsServer to create a metrics server on addr, "host:port".
+func NewMetricsServer(addr string) *MetricsServer {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w)
+	})
+	return &MetricsServer{
+		addr: addr,
+		srv:  &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+	}
+}
+
+func (s *MetricsServer) listen() error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.ln = ln
+	lg.L.Info("started to listen metrics", zap.String("addr", s.addr))
+	return nil
+}
+
+// Start the server, it returns nil after the server is stopped.
+func (s *MetricsServer) Start() error {
+	if err := s.listen(); err != nil {
+		return err
+	}
+	return s.serve()
+}
+
+func (s *MetricsServer) serve() error {
+	if err := s.srv.Serve(s.ln); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Stop the server, the scrapes in hand are cut.
+func (s *MetricsServer) Stop() error {
+	return s.srv.Close()
+}
+
+// writeMetrics to write all the metrics in the Prometheus text format.
+func writeMetrics(out io.Writer) {
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("tracerun_queue_depth", "gauge", "Actions waiting in the queue.")
+	fmt.Fprintf(w, "tracerun_queue_depth %d\n", len(actionChan))
+	metric("tracerun_queue_capacity", "gauge", "Capacity of the action queue.")
+	fmt.Fprintf(w, "tracerun_queue_capacity %d\n", cap(actionChan))
+
+	metric("tracerun_actions_accepted_total", "counter", "Actions accepted in the queue.")
+	fmt.Fprintf(w, "tracerun_actions_accepted_total %d\n", atomic.LoadUint64(&acceptedCount))
+	metric("tracerun_actions_dropped_total", "counter", "Actions dropped by the queue policy.")
+	fmt.Fprintf(w, "tracerun_actions_dropped_total %d\n", atomic.LoadUint64(&droppedCount))
+	metric("tracerun_actions_denied_total", "counter", "Actions denied by the rules.")
+	fmt.Fprintf(w, "tracerun_actions_denied_total %d\n", atomic.LoadUint64(&deniedCount))
+	metric("tracerun_actions_written_total", "counter", "Actions written to the db.")
+	fmt.Fprintf(w, "tracerun_actions_written_total %d\n", atomic.LoadUint64(&writtenCount))
+	metric("tracerun_add_action_errors_total", "counter", "Actions failed to be written to the db.")
+	fmt.Fprintf(w, "tracerun_add_action_errors_total %d\n", atomic.LoadUint64(&addErrorCount))
+
+	metric("tracerun_add_action_duration_seconds", "histogram", "Time to write an action to the db.")
+	var cumulative uint64
+	for i, le := range addBuckets {
+		cumulative += atomic.LoadUint64(&addBucketCount[i])
+		fmt.Fprintf(w, "tracerun_add_action_duration_seconds_bucket{le=\"%g\"} %d\n", le, cumulative)
+	}
+	cumulative += atomic.LoadUint64(&addBucketCount[len(addBuckets)])
+	fmt.Fprintf(w, "tracerun_add_action_duration_seconds_bucket{le=\"+Inf\"} %d\n", cumulative)
+	fmt.Fprintf(w, "tracerun_add_action_duration_seconds_sum %g\n", time.Duration(atomic.LoadUint64(&addNanos)).Seconds())
+	fmt.Fprintf(w, "tracerun_add_action_duration_seconds_count %d\n", cumulative)
+
+	metric("tracerun_open_connections", "gauge", "Connections open on the TCP and Unix socket listeners.")
+	fmt.Fprintf(w, "tracerun_open_connections %d\n", atomic.LoadInt64(&openConns))
+
+	metric("tracerun_route_requests_total", "counter", "Requests by route, over TCP, Unix socket and UDP.")
+	for route := range routeCounts {
+		if n := atomic.LoadUint64(&routeCounts[route]); n != 0 {
+			fmt.Fprintf(w, "tracerun_route_requests_total{route=\"%d\"} %d\n", route, n)
+		}
+	}
+
+	expiryMu.Lock()
+	checks, total := checkCount, checkTotal
+	expiryMu.Unlock()
+	metric("tracerun_check_expirations_duration_seconds", "summary", "Time to check the expired actions.")
+	fmt.Fprintf(w, "tracerun_check_expirations_duration_seconds_sum %g\n", total.Seconds())
+	fmt.Fprintf(w, "tracerun_check_expirations_duration_seconds_count %d\n", checks)
+}
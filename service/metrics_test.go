@@ -0,0 +1,117 @@
+package service
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tracerun/tracerun/lg"
+)
+
+// scrape to get the samples of the metrics server, by name with labels.
+func scrape(t *testing.T, url string) map[string]float64 {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("wrong content type %q", resp.Header.Get("Content-Type"))
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := make(map[string]float64)
+	for _, line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		if i < 0 {
+			t.Fatalf("wrong sample %q", line)
+		}
+		v, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("wrong value of %q: %v", line, err)
+		}
+		samples[line[:i]] = v
+	}
+	return samples
+}
+
+func TestMetrics(t *testing.T) {
+	lg.InitLogger(false, true, "")
+	if err := initQueue(4, PolicyBlock); err != nil {
+		t.Fatal(err)
+	}
+	if err := enqueue(&act{target: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	defer queuedTargets()
+	recordAdd(5*time.Millisecond, nil)
+
+	m := NewMetricsServer("127.0.0.1:8882")
+	if err := m.listen(); err != nil {
+		t.Fatal(err)
+	}
+	go m.serve()
+	defer m.Stop()
+
+	s := NewTCPServer(8883, getRouter())
+	s.Hosts = []string{"127.0.0.1"}
+	if err := s.listen(); err != nil {
+		t.Fatal(err)
+	}
+	go s.serve()
+	defer s.Shutdown(time.Second)
+
+	before := scrape(t, "http://127.0.0.1:8882/metrics")
+
+	conn, err := net.Dial("tcp", "127.0.0.1:8883")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for i := 0; i < 2; i++ {
+		conn.Write(GenerateHeaderBuf(0, 1))
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	after := scrape(t, "http://127.0.0.1:8882/metrics")
+	if after["tracerun_queue_depth"] != 1 || after["tracerun_queue_capacity"] != 4 {
+		t.Errorf("wrong queue %v %v", after["tracerun_queue_depth"], after["tracerun_queue_capacity"])
+	}
+	if n := after[`tracerun_route_requests_total{route="1"}`] - before[`tracerun_route_requests_total{route="1"}`]; n != 2 {
+		t.Errorf("expect 2 pings, got %v", n)
+	}
+	if n := after["tracerun_open_connections"] - before["tracerun_open_connections"]; n != 1 {
+		t.Errorf("expect 1 more connection, got %v", n)
+	}
+
+	count := after["tracerun_add_action_duration_seconds_count"]
+	if count < 1 || after[`tracerun_add_action_duration_seconds_bucket{le="+Inf"}`] != count {
+		t.Errorf("wrong histogram count %v", count)
+	}
+	if after[`tracerun_add_action_duration_seconds_bucket{le="0.01"}`] < 1 {
+		t.Error("the action written in 5ms should be in the 0.01 bucket")
+	}
+	if after[`tracerun_add_action_duration_seconds_bucket{le="0.001"}`] > after[`tracerun_add_action_duration_seconds_bucket{le="0.01"}`] {
+		t.Error("buckets should be cumulative")
+	}
+	for _, name := range []string{
+		"tracerun_actions_accepted_total",
+		"tracerun_actions_written_total",
+		"tracerun_add_action_errors_total",
+		"tracerun_check_expirations_duration_seconds_count",
+	} {
+		if _, ok := after[name]; !ok {
+			t.Errorf("missing %s", name)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Normalize steps applied to the target of an incoming action, in the order
+// of the fields. The zero value keeps targets as they are.
+type Normalize struct {
+	// Home to expand a leading "~" level to, empty to keep it
+	Home string
+	// Clean to drop empty, "." and trailing levels and to resolve ".."
+	Clean bool
+	// Rewrites of target prefixes, the first one matched is applied
+	Rewrites []Rewrite
+	// Lower to lowercase targets, for case-insensitive file systems
+	Lower bool
+}
+
+// Rewrite a target prefix.
+type Rewrite struct {
+	From string
+	To   string
+}
+
+// normalize applied to incoming actions
+var normalize Normalize
+
+// ParseRewrite to parse a rewrite as "from=to".
+func ParseRewrite(s string) (Rewrite, error) {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return Rewrite{}, fmt.Errorf("rewrite %q is not \"from=to\"", s)
+	}
+	return Rewrite{From: s[:i], To: s[i+1:]}, nil
+}
+
+// apply the steps to a target.
+func (n *Normalize) apply(target string) string {
+	if len(n.Home) != 0 && (target == "~" || strings.HasPrefix(target, "~"+separator)) {
+		target = n.Home + target[1:]
+	}
+	if n.Clean {
+		target = cleanTarget(target)
+	}
+	for _, r := range n.Rewrites {
+		if strings.HasPrefix(target, r.From) {
+			target = r.To + target[len(r.From):]
+			break
+		}
+	}
+	if n.Lower {
+		target = strings.ToLower(target)
+	}
+	return target
+}
+
+// cleanTarget to clean a target like a path, a leading separator is kept.
+// ".." above the top level is dropped.
+func cleanTarget(target string) string {
+	var levels []string
+	for _, level := range splitTarget(target) {
+		switch level {
+		case ".":
+		case "..":
+			if len(levels) != 0 {
+				levels = levels[:len(levels)-1]
+			}
+		default:
+			levels = append(levels, level)
+		}
+	}
+
+	cleaned := strings.Join(levels, separator)
+	if strings.HasPrefix(target, separator) {
+		cleaned = separator + cleaned
+	}
+	return cleaned
+}
+
+// ingestTarget to get the canonical target of an incoming action, with an
+// error if it's empty or denied by the rules.
+func ingestTarget(target string) (string, error) {
+	settingsMu.RLock()
+	n := normalize
+	settingsMu.RUnlock()
+
+	target = n.apply(target)
+	if len(target) == 0 {
+		return "", ErrEmptyTarget
+	}
+	if err := checkRules(target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
@@ -0,0 +1,44 @@
+package service
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	n := Normalize{
+		Home:  "/home/user",
+		Clean: true,
+		Rewrites: []Rewrite{
+			{From: "/home/user/work/", To: "work/"},
+			{From: "/home/user/", To: "home/"},
+		},
+		Lower: true,
+	}
+
+	tests := []struct {
+		target string
+		expect string
+	}{
+		{"~/work/Repo/main.go", "work/repo/main.go"},
+		{"/home/user//work/./repo/", "work/repo"},
+		{"~/notes/../todo.md", "home/todo.md"},
+		{"~user/a.go", "~user/a.go"},
+		{"./foo.go", "foo.go"},
+		{"/tmp/x/../../..", "/"},
+	}
+	for _, test := range tests {
+		if got := n.apply(test.target); got != test.expect {
+			t.Errorf("%s: expect %s, got %s", test.target, test.expect, got)
+		}
+	}
+
+	var none Normalize
+	if got := none.apply("./Foo//"); got != "./Foo//" {
+		t.Errorf("zero Normalize should keep the target, got %s", got)
+	}
+
+	if r, err := ParseRewrite("a/=b/"); err != nil || r.From != "a/" || r.To != "b/" {
+		t.Errorf("wrong rewrite %v %v", r, err)
+	}
+	if _, err := ParseRewrite("=b/"); err == nil {
+		t.Error("empty from should be wrong")
+	}
+}
@@ -0,0 +1,31 @@
+//go:build linux
+// +build linux
+
+package service
+
+import (
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/tracerun/tracerun/lg"
+	"go.uber.org/zap"
+)
+
+// peerTrusted to check whether the peer of a Unix socket runs as the user
+// of the service, or as root.
+func peerTrusted(c *net.UnixConn) bool {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		return false
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil || credErr != nil {
+		lg.L.Debug("error get peer credentials", zap.NamedError("control", err), zap.NamedError("cred", credErr))
+		return false
+	}
+	return cred.Uid == 0 || int(cred.Uid) == os.Getuid()
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tracerun/tracerun/lg"
+	"go.uber.org/zap"
+)
+
+// Policies applied when the action queue is full.
+const (
+	// PolicyBlock waits until the queue has room.
+	PolicyBlock = "block"
+	// PolicyDropOldest drops the oldest queued action to make room.
+	PolicyDropOldest = "drop-oldest"
+	// PolicyDropNewest drops the incoming action.
+	PolicyDropNewest = "drop-newest"
+	// PolicyBusy drops the incoming action and replies an error to the client.
+	PolicyBusy = "busy"
+)
+
+var (
+	// ErrBusy replied when the queue is full with PolicyBusy
+	ErrBusy = errors.New("action queue is full")
+	// ErrDropped when the queue is full with PolicyDropNewest
+	ErrDropped = errors.New("action dropped")
+	// ErrBatchTooLarge actions enqueued together beyond the queue capacity
+	ErrBatchTooLarge = errors.New("batch exceeds queue capacity")
+
+	// actionChan is used to handle actions
+	actionChan = make(chan *act, bufferCount)
+	// queuePolicy applied when actionChan is full
+	queuePolicy = PolicyBlock
+	// enqueueMu serializes producers, actions from one connection keep order
+	enqueueMu sync.Mutex
+
+	acceptedCount uint64
+	droppedCount  uint64
+)
+
+type act struct {
+	target string
+	ts     uint32
+	meta   map[string]string
+	// done receives the result of writing, if not nil
+	done chan error
+}
+
+// finish to report the result of writing the action.
+func (a *act) finish(err error) {
+	if a.done != nil {
+		a.done <- err
+	}
+}
+
+// initQueue to create the action queue with the size and the policy.
+func initQueue(size int, policy string) error {
+	if size <= 0 {
+		return fmt.Errorf("queue size must be positive, got %d", size)
+	}
+	switch policy {
+	case PolicyBlock, PolicyDropOldest, PolicyDropNewest, PolicyBusy:
+	default:
+		return fmt.Errorf("unknown queue policy %q", policy)
+	}
+
+	actionChan = make(chan *act, size)
+	queuePolicy = policy
+	atomic.StoreUint64(&acceptedCount, 0)
+	atomic.StoreUint64(&droppedCount, 0)
+	return nil
+}
+
+// enqueue actions according to queuePolicy. The actions are enqueued in order
+// and as a whole: with PolicyDropNewest and PolicyBusy either all of them are
+// queued, or none is and ErrDropped or ErrBusy is returned. More actions than
+// the queue capacity are refused with ErrBatchTooLarge, whatever the policy.
+func enqueue(acts ...*act) error {
+	enqueueMu.Lock()
+	defer enqueueMu.Unlock()
+
+	if len(acts) > cap(actionChan) {
+		return ErrBatchTooLarge
+	}
+
+	// room only grows while producers are locked out
+	if len(acts) > cap(actionChan)-len(actionChan) {
+		switch queuePolicy {
+		case PolicyDropNewest:
+			for _, a := range acts {
+				dropAction(a)
+			}
+			return ErrDropped
+		case PolicyBusy:
+			for _, a := range acts {
+				dropAction(a)
+			}
+			return ErrBusy
+		}
+	}
+
+	for _, a := range acts {
+		if queuePolicy == PolicyDropOldest {
+			pushDropOldest(a)
+		} else {
+			actionChan <- a
+		}
+		atomic.AddUint64(&acceptedCount, 1)
+	}
+	return nil
+}
+
+// pushDropOldest to push an action, dropping the oldest ones while full.
+func pushDropOldest(a *act) {
+	for {
+		select {
+		case actionChan <- a:
+			return
+		default:
+		}
+		select {
+		case old := <-actionChan:
+			dropAction(old)
+		default:
+			// taken by receiveActions meanwhile
+		}
+	}
+}
+
+func dropAction(a *act) {
+	atomic.AddUint64(&droppedCount, 1)
+	lg.L.Warn("action dropped", zap.String("target", a.target), zap.Uint32("ts", a.ts))
+	a.finish(ErrDropped)
+}
+
+// queueStats to get the current state of the action queue.
+func queueStats() *Stats {
+	return &Stats{
+		Accepted: atomic.LoadUint64(&acceptedCount),
+		Dropped:  atomic.LoadUint64(&droppedCount),
+		Queued:   uint32(len(actionChan)),
+		Capacity: uint32(cap(actionChan)),
+		Policy:   queuePolicy,
+		Denied:   atomic.LoadUint64(&deniedCount),
+	}
+}
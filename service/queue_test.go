@@ -0,0 +1,153 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/tracerun/tracerun/lg"
+)
+
+func fillQueue(t *testing.T, policy string) {
+	if err := initQueue(2, policy); err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{"a", "b"} {
+		if err := enqueue(&act{target: target}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func queuedTargets() []string {
+	var targets []string
+	for len(actionChan) > 0 {
+		targets = append(targets, (<-actionChan).target)
+	}
+	return targets
+}
+
+func TestQueuePolicies(t *testing.T) {
+	lg.InitLogger(false, true, "")
+
+	tests := []struct {
+		policy   string
+		err      error
+		accepted uint64
+		targets  []string
+	}{
+		{PolicyDropOldest, nil, 3, []string{"b", "c"}},
+		{PolicyDropNewest, ErrDropped, 2, []string{"a", "b"}},
+		{PolicyBusy, ErrBusy, 2, []string{"a", "b"}},
+	}
+
+	for _, test := range tests {
+		fillQueue(t, test.policy)
+		if err := enqueue(&act{target: "c"}); err != test.err {
+			t.Errorf("%s: expect error %v, got %v", test.policy, test.err, err)
+		}
+
+		stats := queueStats()
+		if stats.Accepted != test.accepted || stats.Dropped != 1 {
+			t.Errorf("%s: wrong stats %v", test.policy, stats)
+		}
+
+		targets := queuedTargets()
+		if len(targets) != 2 || targets[0] != test.targets[0] || targets[1] != test.targets[1] {
+			t.Errorf("%s: expect %v, got %v", test.policy, test.targets, targets)
+		}
+	}
+}
+
+func TestQueueBatch(t *testing.T) {
+	lg.InitLogger(false, true, "")
+
+	if err := initQueue(3, PolicyBusy); err != nil {
+		t.Fatal(err)
+	}
+	if err := enqueue(&act{target: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// no room for the whole batch, nothing is queued
+	if err := enqueue(&act{target: "b"}, &act{target: "c"}, &act{target: "d"}); err != ErrBusy {
+		t.Errorf("expect %v, got %v", ErrBusy, err)
+	}
+	if err := enqueue(&act{target: "b"}, &act{target: "c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	targets := queuedTargets()
+	if len(targets) != 3 || targets[0] != "a" || targets[1] != "b" || targets[2] != "c" {
+		t.Errorf("wrong queue %v", targets)
+	}
+}
+
+func TestQueueBlock(t *testing.T) {
+	lg.InitLogger(false, true, "")
+	fillQueue(t, PolicyBlock)
+
+	done := make(chan error)
+	go func() { done <- enqueue(&act{target: "c"}) }()
+
+	select {
+	case <-done:
+		t.Fatal("enqueue should block on a full queue")
+	default:
+	}
+
+	if a := <-actionChan; a.target != "a" {
+		t.Errorf("expect a, got %s", a.target)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if targets := queuedTargets(); len(targets) != 2 || targets[1] != "c" {
+		t.Errorf("wrong queue %v", targets)
+	}
+}
+
+func TestQueueConfig(t *testing.T) {
+	if err := initQueue(0, PolicyBlock); err == nil {
+		t.Error("size 0 should fail")
+	}
+	if err := initQueue(1, "unknown"); err == nil {
+		t.Error("unknown policy should fail")
+	}
+}
+
+func TestQueueDroppedAck(t *testing.T) {
+	lg.InitLogger(false, true, "")
+
+	if err := initQueue(1, PolicyDropOldest); err != nil {
+		t.Fatal(err)
+	}
+	a := &act{target: "a", done: make(chan error, 1)}
+	if err := enqueue(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := enqueue(&act{target: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-a.done; err != ErrDropped {
+		t.Errorf("expect %v, got %v", ErrDropped, err)
+	}
+	queuedTargets()
+}
+
+func TestBatchTooLarge(t *testing.T) {
+	lg.InitLogger(false, true, "")
+
+	for _, policy := range []string{PolicyBlock, PolicyDropOldest, PolicyDropNewest, PolicyBusy} {
+		if err := initQueue(2, policy); err != nil {
+			t.Fatal(err)
+		}
+		if err := enqueue(&act{target: "a"}, &act{target: "b"}, &act{target: "c"}); err != ErrBatchTooLarge {
+			t.Errorf("%s: expect ErrBatchTooLarge, got %v", policy, err)
+		}
+		if err := enqueue(&act{target: "a"}, &act{target: "b"}); err != nil {
+			t.Errorf("%s: a batch of the capacity should be queued, got %v", policy, err)
+		}
+		if targets := queuedTargets(); len(targets) != 2 {
+			t.Errorf("%s: expect 2 queued, got %v", policy, targets)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/tracerun/tracerun/lg"
+	"go.uber.org/zap"
+)
+
+// ErrNoReload a service started without a way to reload its config
+var ErrNoReload = errors.New("config can't be reloaded")
+
+var (
+	// settingsMu guards the settings changed by a reload
+	settingsMu sync.RWMutex
+
+	// reloadMu guards running, server, udpServer and closing
+	reloadMu sync.Mutex
+	// running the config the service runs with
+	running Config
+	// server accepting the connections
+	server *TCPServer
+	// udpServer receiving the datagrams, nil without UDP
+	udpServer *UDPServer
+	// closing once the service stops, no listener is started after
+	closing bool
+	// retired counts the servers replaced and still shutting down
+	retired sync.WaitGroup
+)
+
+// settings of a config checked, to apply all of them or none.
+type settings struct {
+	cfg      Config
+	interval time.Duration
+	timeout  time.Duration
+	file     string
+	rules    []*rule
+	users    map[string]*account
+}
+
+// checkSettings to check the settings that change without a restart, and
+// read the rules.
+func checkSettings(cfg Config) (*settings, error) {
+	if cfg.MaxClockSkew < 0 || cfg.MaxBackdate < 0 {
+		return nil, errors.New("negative timestamp window")
+	}
+	if cfg.ReadTimeout < 0 || cfg.CheckInterval < 0 {
+		return nil, errors.New("negative timeout or interval")
+	}
+
+	s := &settings{
+		cfg:      cfg,
+		interval: DefaultCheckInterval,
+		timeout:  DefaultReadTimeout,
+		file:     cfg.RulesFile,
+	}
+	if cfg.CheckInterval > 0 {
+		s.interval = cfg.CheckInterval
+	}
+	if s.interval < MinCheckInterval {
+		return nil, ErrCheckInterval
+	}
+	if cfg.ReadTimeout > 0 {
+		s.timeout = cfg.ReadTimeout
+	}
+
+	if len(s.file) == 0 {
+		s.file = cfg.DBFolder + ".rules"
+	}
+	var err error
+	if s.rules, err = readRules(s.file); err != nil {
+		return nil, err
+	}
+
+	if cfg.Auth {
+		file := cfg.UsersFile
+		if len(file) == 0 {
+			file = cfg.DBFolder + ".users"
+		}
+		if s.users, err = readUsers(file); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// apply the settings checked.
+func (s *settings) apply() {
+	lg.SetDebug(s.cfg.Debug)
+
+	settingsMu.Lock()
+	maxClockSkew, maxBackdate = s.cfg.MaxClockSkew, s.cfg.MaxBackdate
+	readTimeout = s.timeout
+	normalize = s.cfg.Normalize
+	exitDisabled = s.cfg.DisableExit
+	settingsMu.Unlock()
+
+	setCheckInterval(s.interval)
+	setRules(s.file, s.rules)
+	setUsers(s.users)
+}
+
+// changedSettings to list the settings of cfg different from old.
+func changedSettings(old, cfg Config) []string {
+	var names []string
+	add := func(name string, changed bool) {
+		if changed {
+			names = append(names, name)
+		}
+	}
+	add("debug", old.Debug != cfg.Debug)
+	add("listen", listenChanged(old, cfg))
+	add("read-timeout", old.ReadTimeout != cfg.ReadTimeout)
+	add("check-interval", old.CheckInterval != cfg.CheckInterval)
+	add("max-skew", old.MaxClockSkew != cfg.MaxClockSkew)
+	add("max-backdate", old.MaxBackdate != cfg.MaxBackdate)
+	add("rules", old.RulesFile != cfg.RulesFile)
+	add("users", old.UsersFile != cfg.UsersFile)
+	add("no-exit", old.DisableExit != cfg.DisableExit)
+	add("normalize", !reflect.DeepEqual(old.Normalize, cfg.Normalize))
+	return names
+}
+
+// listenChanged to check whether cfg listens on other addresses than old.
+func listenChanged(old, cfg Config) bool {
+	return old.Port != cfg.Port || !reflect.DeepEqual(listenHosts(old.Listen), listenHosts(cfg.Listen))
+}
+
+// udpMoved to check whether the datagrams of old move to other addresses by
+// the listen hosts or the port of cfg. UDP on or off, or its own port, only
+// change with a restart.
+func udpMoved(old, cfg Config) bool {
+	if !old.UDP {
+		return false
+	}
+	return !reflect.DeepEqual(listenHosts(old.Listen), listenHosts(cfg.Listen)) ||
+		old.UDPPort == 0 && old.Port != cfg.Port
+}
+
+// restartNeeded to list the settings of cfg different from old which only
+// change with a restart.
+func restartNeeded(old, cfg Config) []string {
+	var names []string
+	if old.DBFolder != cfg.DBFolder {
+		names = append(names, "db")
+	}
+	if old.QueueSize != cfg.QueueSize {
+		names = append(names, "queue-size")
+	}
+	if old.QueuePolicy != cfg.QueuePolicy {
+		names = append(names, "queue-policy")
+	}
+	if old.Separator != cfg.Separator {
+		names = append(names, "separator")
+	}
+	if old.Socket != cfg.Socket || old.SocketMode != cfg.SocketMode {
+		names = append(names, "socket")
+	}
+	if old.UDP != cfg.UDP || old.UDPPort != cfg.UDPPort {
+		names = append(names, "udp")
+	}
+	if old.Auth != cfg.Auth || old.TokenFile != cfg.TokenFile {
+		names = append(names, "auth")
+	}
+	if old.Metrics != cfg.Metrics {
+		names = append(names, "metrics")
+	}
+	if old.MetaFile != cfg.MetaFile {
+		names = append(names, "meta")
+	}
+	if old.TLSCert != cfg.TLSCert || old.TLSKey != cfg.TLSKey || old.ClientCA != cfg.ClientCA {
+		names = append(names, "tls")
+	}
+	return names
+}
+
+// reloadConfig to get the config again and apply it, without losing the
+// queued actions. Nothing is applied if the config is wrong or the new
+// listeners can't start. The rules and users files are read again even if
+// unchanged.
+func reloadConfig() (*ReloadResult, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	if running.Reload == nil {
+		return nil, ErrNoReload
+	}
+	cfg, err := running.Reload()
+	if err != nil {
+		return nil, err
+	}
+	s, err := checkSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	moved := udpMoved(running, cfg)
+	if moved {
+		if err := restartUDP(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if listenChanged(running, cfg) {
+		if err := restartListener(cfg); err != nil {
+			if moved {
+				// back to the addresses of the TCP listener kept
+				if err := restartUDP(running); err != nil {
+					lg.L.Error("error listen datagrams again", zap.Error(err))
+				}
+			}
+			return nil, err
+		}
+	}
+	s.apply()
+
+	result := &ReloadResult{
+		Changed: changedSettings(running, cfg),
+		Restart: restartNeeded(running, cfg),
+	}
+	// the settings needing a restart keep running as they are
+	cfg.DBFolder, cfg.QueueSize = running.DBFolder, running.QueueSize
+	cfg.QueuePolicy, cfg.Separator = running.QueuePolicy, running.Separator
+	cfg.Socket, cfg.SocketMode = running.Socket, running.SocketMode
+	cfg.UDP, cfg.UDPPort = running.UDP, running.UDPPort
+	cfg.Auth, cfg.TokenFile = running.Auth, running.TokenFile
+	cfg.Metrics, cfg.MetaFile = running.Metrics, running.MetaFile
+	cfg.TLSCert, cfg.TLSKey, cfg.ClientCA = running.TLSCert, running.TLSKey, running.ClientCA
+	cfg.Reload = running.Reload
+	running = cfg
+
+	lg.L.Info("config reloaded", zap.Strings("changed", result.Changed), zap.Strings("restart", result.Restart))
+	return result, nil
+}
+
+// restartListener to listen on the address of cfg, the connections of the
+// old listener are shut down in the background.
+func restartListener(cfg Config) error {
+	s := NewTCPServer(cfg.Port, server.router)
+	s.Hosts = listenHosts(cfg.Listen)
+	s.TLS = server.TLS
+	if err := s.listen(); err != nil {
+		// the old listener may hold the port, like on a wider host
+		server.Stop()
+		if err = s.listen(); err != nil {
+			old := NewTCPServer(running.Port, server.router)
+			old.Hosts = listenHosts(running.Listen)
+			old.TLS = server.TLS
+			if errOld := old.listen(); errOld != nil {
+				lg.L.Error("error listen again", zap.Error(errOld))
+				requestStop()
+				return err
+			}
+			go serve(old)
+			retire(server)
+			server = old
+			return err
+		}
+	}
+
+	go serve(s)
+	retire(server)
+	server = s
+	return nil
+}
+
+// restartUDP to receive the datagrams on the listen hosts and port of cfg,
+// the UDP port running is kept. The old sockets are closed after, or before
+// if they hold the port.
+func restartUDP(cfg Config) error {
+	cfg.UDPPort = running.UDPPort
+	s := NewUDPServer(udpPort(cfg), udpServer.router)
+	s.Hosts = listenHosts(cfg.Listen)
+	if err := s.listen(); err != nil {
+		udpServer.Stop()
+		if err = s.listen(); err != nil {
+			back := NewUDPServer(udpPort(running), udpServer.router)
+			back.Hosts = listenHosts(running.Listen)
+			if errOld := back.listen(); errOld != nil {
+				lg.L.Error("error listen datagrams again", zap.Error(errOld))
+				requestStop()
+				return err
+			}
+			go serveUDP(back)
+			udpServer = back
+			return err
+		}
+	}
+
+	go serveUDP(s)
+	if err := udpServer.Stop(); err != nil {
+		lg.L.Warn("error stop UDP service", zap.Error(err))
+	}
+	udpServer = s
+	return nil
+}
+
+// serveUDP the datagrams of a server listening, the service stops if it
+// fails.
+func serveUDP(s *UDPServer) {
+	if err := s.serve(); err != nil {
+		lg.L.Error("error serve UDP service", zap.Error(err))
+		requestStop()
+	}
+}
+
+// retire a server replaced, its connections are shut down in the background.
+func retire(s *TCPServer) {
+	retired.Add(1)
+	go func() {
+		defer retired.Done()
+		stopServer(s)
+	}()
+}
+
+// serve the connections of a server listening, the service stops if it
+// fails.
+func serve(s *TCPServer) {
+	if err := s.serve(); err != nil {
+		lg.L.Error("error serve TCP service", zap.Error(err))
+		requestStop()
+	}
+}
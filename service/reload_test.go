@@ -0,0 +1,99 @@
+package service
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/tracerun/tracerun/lg"
+)
+
+func TestReloadConfig(t *testing.T) {
+	lg.InitLogger(false, true, "")
+	defer setCheckInterval(DefaultCheckInterval)
+
+	cfg := Config{
+		Port:         8873,
+		DBFolder:     t.TempDir() + "/db",
+		QueueSize:    10,
+		MaxClockSkew: time.Minute,
+		MaxBackdate:  time.Hour,
+		UDP:          true,
+	}
+	s, err := checkSettings(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.apply()
+
+	reloadMu.Lock()
+	running = cfg
+	server = NewTCPServer(cfg.Port, getRouter())
+	reloadMu.Unlock()
+	if err := server.listen(); err != nil {
+		t.Fatal(err)
+	}
+	go serve(server)
+	udpServer = NewUDPServer(udpPort(cfg), udpRouter())
+	udpServer.Hosts = listenHosts(cfg.Listen)
+	if err := udpServer.listen(); err != nil {
+		t.Fatal(err)
+	}
+	go serveUDP(udpServer)
+
+	next := cfg
+	next.Port = 8874
+	next.QueueSize = 20
+	next.CheckInterval = 10 * time.Second
+	running.Reload = func() (Config, error) { return next, nil }
+
+	result, err := reloadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Changed) != 2 || result.Changed[0] != "listen" || result.Changed[1] != "check-interval" {
+		t.Errorf("wrong changed %v", result.Changed)
+	}
+	if len(result.Restart) != 1 || result.Restart[0] != "queue-size" {
+		t.Errorf("wrong restart %v", result.Restart)
+	}
+	if d := getCheckInterval(); d != 10*time.Second {
+		t.Errorf("expect interval 10s, got %s", d)
+	}
+	if running.QueueSize != 10 {
+		t.Errorf("queue size should be kept until a restart, got %d", running.QueueSize)
+	}
+	c, err := net.Dial("tcp", "127.0.0.1:8874")
+	if err != nil {
+		t.Fatal("new listener", err)
+	}
+	c.Close()
+	// the datagrams moved with the TCP port
+	if uc, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8874}); err == nil {
+		uc.Close()
+		t.Error("datagrams should be received on the new port")
+	}
+	uc, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8873})
+	if err != nil {
+		t.Fatal("old UDP port still held", err)
+	}
+	uc.Close()
+
+	// a wrong config changes nothing
+	next.MaxBackdate = -time.Hour
+	if _, err := reloadConfig(); err == nil {
+		t.Error("negative backdate should fail")
+	}
+	if maxBackdate != time.Hour {
+		t.Errorf("backdate changed to %s", maxBackdate)
+	}
+
+	server.Shutdown(time.Second)
+	retired.Wait()
+	udpServer.Stop()
+	udpServer = nil
+	running.Reload = nil
+	if _, err := reloadConfig(); err != ErrNoReload {
+		t.Errorf("expect ErrNoReload, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Buckets of a report.
+const (
+	BucketHour  = "hour"
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// bucketStart to get the start of the bucket containing t. Weeks start on
+// Monday.
+func bucketStart(t time.Time, bucket string) time.Time {
+	y, m, d := t.Date()
+	switch bucket {
+	case BucketHour:
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location())
+	case BucketWeek:
+		days := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-days, 0, 0, 0, 0, t.Location())
+	case BucketMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+}
+
+func nextBucket(t time.Time, bucket string) time.Time {
+	switch bucket {
+	case BucketHour:
+		return t.Add(time.Hour)
+	case BucketWeek:
+		return t.AddDate(0, 0, 7)
+	case BucketMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+func checkBucket(bucket string) error {
+	switch bucket {
+	case BucketHour, BucketDay, BucketWeek, BucketMonth:
+		return nil
+	default:
+		return fmt.Errorf("unknown bucket %q", bucket)
+	}
+}
+
+// sumSlots to add the seconds of slots to sums by bucket start. Slots across
+// buckets are split, the part outside [start, end) is ignored, 0 end for no
+// limit.
+func sumSlots(sums map[uint32]uint64, slots []*Slot, bucket string, loc *time.Location, start, end uint32) {
+	for _, slot := range slots {
+		from, to := clipSlot(slot, start, end)
+		for from < to {
+			b := bucketStart(time.Unix(int64(from), 0).In(loc), bucket)
+			next := uint32(nextBucket(b, bucket).Unix())
+			if next > to {
+				next = to
+			}
+			sums[uint32(b.Unix())] += uint64(next - from)
+			from = next
+		}
+	}
+}
+
+// clipSlot to get the part of a slot in [start, end), 0 end for no limit.
+// The part is empty if from >= to.
+func clipSlot(slot *Slot, start, end uint32) (uint32, uint32) {
+	from, to := slot.Start, slot.Start+slot.Slot
+	if from < start {
+		from = start
+	}
+	if end != 0 && to > end {
+		to = end
+	}
+	return from, to
+}
+
+// totalSeconds to sum the seconds of slots in [start, end).
+func totalSeconds(slots []*Slot, start, end uint32) uint64 {
+	var total uint64
+	for _, slot := range slots {
+		if from, to := clipSlot(slot, start, end); from < to {
+			total += uint64(to - from)
+		}
+	}
+	return total
+}
+
+// buildReport to sum the slot durations of targets by bucket, in the zone of
+// the db. No targets for all of them. With a depth, targets are rolled up to
+// their prefix of depth levels. Targets are the ones in the namespace scope.
+func buildReport(req *ReportRequest, scope string) (*Report, error) {
+	bucket := req.Bucket
+	if len(bucket) == 0 {
+		bucket = BucketDay
+	}
+	if err := checkBucket(bucket); err != nil {
+		return nil, err
+	}
+
+	offset, err := db.ZoneOffset()
+	if err != nil {
+		return nil, err
+	}
+	loc := time.FixedZone("", int(offset))
+
+	targets := req.Targets
+	if len(targets) == 0 {
+		targets = scopeTargets(scope, db.GetTargets())
+	}
+
+	sumsByTarget := make(map[string]map[uint32]uint64)
+	var keys []string
+	for _, target := range targets {
+		if !underPrefix(target, req.Prefix) {
+			continue
+		}
+		startsResult, slotsResult, err := db.GetSlots(scope+target, req.Start, req.End)
+		if err != nil {
+			return nil, err
+		}
+
+		key := rollUp(target, req.Depth)
+		sums, ok := sumsByTarget[key]
+		if !ok {
+			sums = make(map[uint32]uint64)
+			sumsByTarget[key] = sums
+			keys = append(keys, key)
+		}
+		sumSlots(sums, flattenSlots(startsResult, slotsResult), bucket, loc, req.Start, req.End)
+	}
+	sort.Strings(keys)
+
+	report := &Report{ZoneOffset: offset}
+	for _, key := range keys {
+		report.Rows = append(report.Rows, reportRows(key, sumsByTarget[key])...)
+	}
+	return report, nil
+}
+
+func reportRows(target string, sums map[uint32]uint64) []*Report_Row {
+	var rows []*Report_Row
+	for b, seconds := range sums {
+		if seconds == 0 {
+			continue
+		}
+		rows = append(rows, &Report_Row{
+			Target:  target,
+			Bucket:  b,
+			Seconds: seconds,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Bucket < rows[j].Bucket })
+	return rows
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSumSlots(t *testing.T) {
+	loc := time.FixedZone("", 8*3600)
+	// 2017-07-16 23:30 to 2017-07-17 01:00 in loc, Sunday to Monday
+	from := time.Date(2017, 7, 16, 23, 30, 0, 0, loc)
+	slots := []*Slot{{Start: uint32(from.Unix()), Slot: 5400}}
+
+	day1 := uint32(time.Date(2017, 7, 16, 0, 0, 0, 0, loc).Unix())
+	day2 := uint32(time.Date(2017, 7, 17, 0, 0, 0, 0, loc).Unix())
+	week1 := uint32(time.Date(2017, 7, 10, 0, 0, 0, 0, loc).Unix())
+	week2 := day2
+	month := uint32(time.Date(2017, 7, 1, 0, 0, 0, 0, loc).Unix())
+
+	tests := []struct {
+		bucket string
+		expect map[uint32]uint64
+	}{
+		{BucketDay, map[uint32]uint64{day1: 1800, day2: 3600}},
+		{BucketWeek, map[uint32]uint64{week1: 1800, week2: 3600}},
+		{BucketMonth, map[uint32]uint64{month: 5400}},
+		{BucketHour, map[uint32]uint64{uint32(from.Unix()) - 1800: 1800, day2: 3600}},
+	}
+
+	for _, test := range tests {
+		sums := make(map[uint32]uint64)
+		sumSlots(sums, slots, test.bucket, loc, 0, 0)
+		if len(sums) != len(test.expect) {
+			t.Errorf("%s: expect %v, got %v", test.bucket, test.expect, sums)
+			continue
+		}
+		for b, seconds := range test.expect {
+			if sums[b] != seconds {
+				t.Errorf("%s: expect %v, got %v", test.bucket, test.expect, sums)
+			}
+		}
+	}
+
+	// clipped by the range end
+	sums := make(map[uint32]uint64)
+	sumSlots(sums, slots, BucketDay, loc, 0, day2+600)
+	if sums[day1] != 1800 || sums[day2] != 600 {
+		t.Errorf("wrong clipped sums %v", sums)
+	}
+
+	if err := checkBucket("year"); err == nil {
+		t.Error("year should be unknown")
+	}
+}
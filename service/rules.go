@@ -0,0 +1,169 @@
+package service
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tracerun/tracerun/lg"
+	"go.uber.org/zap"
+)
+
+// Actions of a rule.
+const (
+	RuleAllow = "allow"
+	RuleDeny  = "deny"
+)
+
+// Kinds of rule pattern.
+const (
+	RuleGlob  = "glob"
+	RuleRegex = "regex"
+)
+
+// ErrDenied an action denied by the rules
+var ErrDenied = errors.New("target denied by rules")
+
+var (
+	// rulesFile to load the rules from, empty for no rules
+	rulesFile string
+
+	rulesMu     sync.RWMutex
+	activeRules []*rule
+
+	deniedCount uint64
+)
+
+// rule to allow or deny the targets matched.
+type rule struct {
+	action  string
+	kind    string
+	pattern string
+	m       *matcher
+}
+
+// parseRules to read the rules, one a line as "<allow|deny> <glob|regex>
+// <pattern>". Empty lines and lines starting with "#" are skipped.
+func parseRules(r io.Reader) ([]*rule, error) {
+	var rules []*rule
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// the pattern is the rest of the line, spaces inside are kept
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("rules line %d: want \"<allow|deny> <glob|regex> <pattern>\"", n)
+		}
+		rest := strings.TrimSpace(line[len(fields[0]):])
+		ru := &rule{
+			action:  fields[0],
+			kind:    fields[1],
+			pattern: strings.TrimSpace(rest[len(fields[1]):]),
+		}
+		if ru.action != RuleAllow && ru.action != RuleDeny {
+			return nil, fmt.Errorf("rules line %d: unknown action %q", n, ru.action)
+		}
+
+		var err error
+		switch ru.kind {
+		case RuleGlob:
+			ru.m, err = newMatcher(ru.pattern, "")
+		case RuleRegex:
+			ru.m, err = newMatcher("", ru.pattern)
+		default:
+			err = fmt.Errorf("unknown kind %q", ru.kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rules line %d: %v", n, err)
+		}
+		rules = append(rules, ru)
+	}
+	return rules, scanner.Err()
+}
+
+// loadRules to replace the rules with the ones in the rules file. The rules
+// are kept if the file is wrong.
+func loadRules() error {
+	rulesMu.RLock()
+	file := rulesFile
+	rulesMu.RUnlock()
+
+	rules, err := readRules(file)
+	if err != nil {
+		return err
+	}
+	setRules(file, rules)
+	return nil
+}
+
+// readRules to read the rules of a file, a missing file is no rules.
+func readRules(file string) ([]*rule, error) {
+	if len(file) == 0 {
+		return nil, nil
+	}
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseRules(f)
+}
+
+func setRules(file string, rules []*rule) {
+	rulesMu.Lock()
+	rulesFile, activeRules = file, rules
+	rulesMu.Unlock()
+	lg.L.Info("rules loaded", zap.String("file", file), zap.Int("rules", len(rules)))
+}
+
+// allowed to check a target against the rules, the last rule matched wins.
+// A target matched by no rule is allowed.
+func allowed(rules []*rule, target string) bool {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].m.match(target) {
+			return rules[i].action == RuleAllow
+		}
+	}
+	return true
+}
+
+// checkRules to get ErrDenied if the active rules deny the target.
+func checkRules(target string) error {
+	rulesMu.RLock()
+	ok := allowed(activeRules, target)
+	rulesMu.RUnlock()
+
+	if !ok {
+		atomic.AddUint64(&deniedCount, 1)
+		return ErrDenied
+	}
+	return nil
+}
+
+// listRules to get the active rules.
+func listRules() *Rules {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+
+	list := &Rules{File: rulesFile}
+	for _, ru := range activeRules {
+		list.Rules = append(list.Rules, &Rules_Rule{
+			Action:  ru.action,
+			Kind:    ru.kind,
+			Pattern: ru.pattern,
+		})
+	}
+	return list
+}
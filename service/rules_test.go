@@ -0,0 +1,54 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := parseRules(strings.NewReader(`
+# noise
+deny glob **/node_modules/**
+deny regex \.(tmp|swp)$
+deny	glob  **/.git/**
+allow   glob	project/.git/hooks/*
+deny glob  My Documents/*
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 5 {
+		t.Fatalf("expect 5 rules, got %d", len(rules))
+	}
+	if rules[4].pattern != "My Documents/*" {
+		t.Errorf("wrong pattern %q", rules[4].pattern)
+	}
+
+	tests := []struct {
+		target string
+		expect bool
+	}{
+		{"project/main.go", true},
+		{"project/web/node_modules/a/index.js", false},
+		{"node_modules/a.js", false},
+		{"project/main.go.swp", false},
+		{"project/.git/HEAD", false},
+		{"project/.git/hooks/pre-commit", true},
+		{"My Documents/a.txt", false},
+	}
+	for _, test := range tests {
+		if got := allowed(rules, test.target); got != test.expect {
+			t.Errorf("%s: expect allowed %v, got %v", test.target, test.expect, got)
+		}
+	}
+
+	if !allowed(nil, "any") {
+		t.Error("no rules should allow all")
+	}
+
+	for _, wrong := range []string{"deny **/tmp", "block glob *.tmp", "deny path *.tmp", "deny regex ("} {
+		if _, err := parseRules(strings.NewReader(wrong)); err == nil {
+			t.Errorf("%q should be wrong", wrong)
+		}
+	}
+}
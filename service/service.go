@@ -3,18 +3,25 @@ package service
 import (
 	"encoding/binary"
 	"errors"
-	"fmt"
 	"io"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/tracerun/tdb"
 	"github.com/tracerun/tracerun/lg"
+	"go.uber.org/zap"
 )
 
+// DefaultListen host of the TCP service, only local clients can connect.
+const DefaultListen = "127.0.0.1"
+
 const (
 	headerBytes = 3
+	// seconds to wait for open connections while shutting down
+	shutdownTimeout = 10
 )
 
 var (
@@ -28,34 +35,279 @@ var (
 // RouteFunc to route handlers
 type RouteFunc func([]byte, io.Writer)
 
-// Start service
-func Start(port uint16, dbFolder string) {
+// Config of the service
+type Config struct {
+	// Port for TCP service
+	Port uint16
+	// Listen hosts of the TCP service, a host or "host:port" with its own
+	// port. DefaultListen if empty.
+	Listen []string
+	// Socket path of a Unix socket to listen on too, empty for none
+	Socket string
+	// SocketMode permissions of the socket file, 0 for DefaultSocketMode
+	SocketMode os.FileMode
+	// UDP to receive actions by datagram on the listen hosts too, refused
+	// with TLSCert as datagrams are plaintext
+	UDP bool
+	// UDPPort for datagrams, 0 to share Port
+	UDPPort uint16
+	// Auth to ask a token for all the routes but ping, hello and auth
+	Auth bool
+	// TokenFile of the token, "<DBFolder>.token" if empty. It's generated
+	// if missing.
+	TokenFile string
+	// Metrics "host:port" of an HTTP listener exposing Prometheus metrics
+	// on /metrics, none if empty
+	Metrics string
+	// DisableExit to refuse the legacy exit route, the admin route stops the
+	// service instead
+	DisableExit bool
+	// UsersFile of the users and their tokens, "<DBFolder>.users" if
+	// empty. Read with Auth only, none if missing.
+	UsersFile string
+	// TLSCert and TLSKey files of the TCP listeners, plaintext if empty
+	TLSCert string
+	TLSKey  string
+	// ClientCA file to verify the client certificates, none asked if empty
+	ClientCA string
+	// ReadTimeout to close a connection without frames, 0 for the default
+	ReadTimeout time.Duration
+	// CheckInterval between two checks of expired actions, 0 for the default
+	CheckInterval time.Duration
+	// DBFolder path for db folder
+	DBFolder string
+	// QueueSize capacity of the action queue
+	QueueSize int
+	// QueuePolicy applied when the action queue is full, one of the Policy*
+	QueuePolicy string
+	// MaxClockSkew how far in the future a client timestamp is accepted
+	MaxClockSkew time.Duration
+	// MaxBackdate how far in the past a client timestamp is accepted
+	MaxBackdate time.Duration
+	// Separator between the levels of a target
+	Separator string
+	// RulesFile to allow or deny targets, "<DBFolder>.rules" if empty
+	RulesFile string
+	// MetaFile the metadata of the actions is appended to,
+	// "<DBFolder>.meta" if empty
+	MetaFile string
+	// Normalize the targets of incoming actions before the rules
+	Normalize Normalize
+	// Debug to log in debug level
+	Debug bool
+	// Reload to get the config again, nil if it can't be reloaded. The
+	// settings which only change with a restart are kept.
+	Reload func() (Config, error)
+}
+
+// Start service, it returns after the service stopped. The config is got
+// again by cfg.Reload on SIGHUP or route 7.
+func Start(cfg Config) error {
+	if cfg.UDP && len(cfg.TLSCert) != 0 {
+		return ErrUDPWithTLS
+	}
+	if err := initQueue(cfg.QueueSize, cfg.QueuePolicy); err != nil {
+		return err
+	}
+	if len(cfg.Separator) == 0 {
+		return ErrSeparator
+	}
+	separator = cfg.Separator
+	s, err := checkSettings(cfg)
+	if err != nil {
+		return err
+	}
+	s.apply()
+
+	setToken("")
+	if cfg.Auth {
+		file := cfg.TokenFile
+		if len(file) == 0 {
+			file = cfg.DBFolder + ".token"
+		}
+		t, err := loadToken(file)
+		if err != nil {
+			return err
+		}
+		setToken(t)
+		lg.L.Info("authentication on", zap.String("token file", file))
+	}
+
+	tlsCfg, err := TLSConfig(cfg.TLSCert, cfg.TLSKey, cfg.ClientCA)
+	if err != nil {
+		return err
+	}
+
+	db, err = tdb.Open(cfg.DBFolder)
+	if err != nil {
+		return err
+	}
+	metaPath := cfg.MetaFile
+	if len(metaPath) == 0 {
+		metaPath = cfg.DBFolder + ".meta"
+	}
+	if err := openMetaFile(metaPath); err != nil {
+		db.Close()
+		return err
+	}
+	if err := loadOpenTargets(); err != nil {
+		closeMetaFile()
+		db.Close()
+		return err
+	}
+
+	workers.Add(2)
 	go receiveActions()
 	go checkActions()
 
-	var err error
-	db, err = tdb.Open(dbFolder)
-	if err != nil {
-		panic(err)
+	reloadMu.Lock()
+	running, closing = cfg, false
+	server = NewTCPServer(cfg.Port, getRouter())
+	server.Hosts = listenHosts(cfg.Listen)
+	server.TLS = tlsCfg
+	go func(s *TCPServer) {
+		if err := s.Start(); err != nil {
+			lg.L.Error("error start TCP service", zap.Error(err))
+			requestStop()
+		}
+	}(server)
+	reloadMu.Unlock()
+
+	var unixServer *TCPServer
+	if len(cfg.Socket) != 0 {
+		mode := cfg.SocketMode
+		if mode == 0 {
+			mode = DefaultSocketMode
+		}
+		unixServer = NewUnixServer(cfg.Socket, mode, getRouter())
+		go func() {
+			if err := unixServer.Start(); err != nil {
+				lg.L.Error("error start Unix socket service", zap.Error(err))
+				requestStop()
+			}
+		}()
 	}
 
-	s := NewTCPServer(port, getRouter())
-	go s.Start()
+	if cfg.UDP {
+		reloadMu.Lock()
+		udpServer = NewUDPServer(udpPort(cfg), udpRouter())
+		udpServer.Hosts = listenHosts(cfg.Listen)
+		go func(s *UDPServer) {
+			if err := s.Start(); err != nil {
+				lg.L.Error("error start UDP service", zap.Error(err))
+				requestStop()
+			}
+		}(udpServer)
+		reloadMu.Unlock()
+	}
+
+	var metricsServer *MetricsServer
+	if len(cfg.Metrics) != 0 {
+		metricsServer = NewMetricsServer(cfg.Metrics)
+		go func() {
+			if err := metricsServer.Start(); err != nil {
+				lg.L.Error("error start metrics service", zap.Error(err))
+				requestStop()
+			}
+		}()
+	}
 
 	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, os.Interrupt)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 
-	select {
-	case <-stopChan:
-	case <-sigs:
+	for stopping := false; !stopping; {
+		select {
+		case <-stopChan:
+			lg.L.Info("stop requested")
+			stopping = true
+		case sig := <-sigs:
+			lg.L.Info("signal received", zap.Stringer("signal", sig))
+			if sig != syscall.SIGHUP {
+				stopping = true
+			} else if _, err := reloadConfig(); err != nil {
+				lg.L.Error("error reloading config", zap.Error(err))
+			}
+		}
 	}
+	signal.Stop(sigs)
+
+	// no more reload or restart, one waiting for the lock fails
+	reloadMu.Lock()
+	running.Reload, closing = nil, true
+	srv, udpSrv := server, udpServer
+	reloadMu.Unlock()
 
-	stop(s)
+	// no more actions by datagram
+	if udpSrv != nil {
+		if err := udpSrv.Stop(); err != nil {
+			lg.L.Warn("error stop UDP service", zap.Error(err))
+		}
+	}
+
+	if unixServer != nil {
+		stopServer(unixServer)
+	}
+	stop(srv)
+	if metricsServer != nil {
+		if err := metricsServer.Stop(); err != nil {
+			lg.L.Warn("error stop metrics service", zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// listenHosts to get the hosts to listen on, DefaultListen if none.
+func listenHosts(hosts []string) []string {
+	if len(hosts) == 0 {
+		return []string{DefaultListen}
+	}
+	return hosts
 }
 
+// udpPort to get the port of the datagrams, UDPPort or the TCP port.
+func udpPort(cfg Config) uint16 {
+	if cfg.UDPPort != 0 {
+		return cfg.UDPPort
+	}
+	return cfg.Port
+}
+
+// requestStop to ask Start to stop the service, it never blocks.
+func requestStop() {
+	select {
+	case stopChan <- true:
+	default:
+		// a stop is already requested
+	}
+}
+
+// stop the service: stop accepting connections, wait for the open ones,
+// write all the queued actions, check expirations once more and close db.
 func stop(s *TCPServer) {
-	s.ln.Close()
+	stopServer(s)
+	// the listeners replaced by a reload
+	retired.Wait()
 	lg.L.Info("TCP service stopped.")
+
+	// no more connections, so no more actions
+	close(quitChan)
+	workers.Wait()
+	lg.L.Info("actions drained", zap.Int("count", drainActions()))
+
+	checkExpirations()
+	if err := db.Close(); err != nil {
+		lg.L.Error("error close db", zap.Error(err))
+	}
+	if err := closeMetaFile(); err != nil {
+		lg.L.Error("error close metadata file", zap.Error(err))
+	}
+	lg.L.Info("db closed.")
+}
+
+func stopServer(s *TCPServer) {
+	if err := s.Shutdown(shutdownTimeout * time.Second); err != nil {
+		lg.L.Warn("error shutdown TCP service", zap.Error(err))
+	}
 }
 
 // WriteErrorMessage to write a message to writer
@@ -64,9 +316,7 @@ func WriteErrorMessage(err error, w io.Writer) {
 	errMsg.Message = err.Error()
 
 	buf, _ := proto.Marshal(&errMsg)
-	headerBuf := GenerateHeaderBuf(uint16(len(buf)), uint8(255))
-
-	w.Write(append(headerBuf, buf...))
+	writeFrame(w, uint8(255), buf)
 }
 
 // GenerateHeaderBuf to generate a header buf
@@ -79,29 +329,6 @@ func GenerateHeaderBuf(length uint16, route uint8) []byte {
 
 // ReadOne to read header containing data count and route info
 func ReadOne(r io.Reader) ([]byte, uint8, error) {
-	byteCount := uint16(0)
-	route := uint8(0)
-
-	headerBuf := make([]byte, headerBytes)
-	n, err := io.ReadFull(r, headerBuf)
-	if err != nil {
-		return nil, route, err
-	}
-	if n != headerBytes {
-		return nil, route, fmt.Errorf("read header wrong")
-	}
-
-	byteCount = binary.LittleEndian.Uint16(headerBuf)
-	route = uint8(headerBuf[2])
-
-	buf := make([]byte, byteCount)
-	n, err = io.ReadFull(r, buf)
-	if err != nil {
-		return nil, route, err
-	}
-	if n != int(byteCount) {
-		return nil, route, ErrDataLength
-	}
-
-	return buf, route, nil
+	buf, route, _, err := ReadFrame(r, Version1)
+	return buf, route, err
 }
@@ -10,11 +10,31 @@ It is generated from these files:
 
 It has these top-level messages:
 	Meta
+	Hello
+	Stats
 	AllActions
+	TimedAction
+	Ack
+	ActionBatch
+	BatchResult
+	Filter
 	Targets
 	SlotRange
 	Slot
 	Slots
+	ReportRequest
+	Report
+	TreeRequest
+	TargetNode
+	RulesRequest
+	Rules
+	ExpiryRequest
+	Expiry
+	ReloadResult
+	AuthRequest
+	AuthResult
+	AdminRequest
+	AdminResult
 	ErrorMessage
 */
 package service
@@ -43,6 +63,11 @@ type Meta struct {
 	Arch       string `protobuf:"bytes,6,opt,name=arch" json:"arch,omitempty"`
 	Os         string `protobuf:"bytes,7,opt,name=os" json:"os,omitempty"`
 	ZoneOffset int32  `protobuf:"varint,8,opt,name=zone_offset,json=zoneOffset" json:"zone_offset,omitempty"`
+	// user and host the caller is authenticated as.
+	CallerUser string `protobuf:"bytes,9,opt,name=caller_user,json=callerUser" json:"caller_user,omitempty"`
+	CallerHost string `protobuf:"bytes,10,opt,name=caller_host,json=callerHost" json:"caller_host,omitempty"`
+	// frame version agreed when the request is a Hello, 0 otherwise.
+	FrameVersion uint32 `protobuf:"varint,11,opt,name=frame_version,json=frameVersion" json:"frame_version,omitempty"`
 }
 
 func (m *Meta) Reset()                    { *m = Meta{} }
@@ -106,6 +131,101 @@ func (m *Meta) GetZoneOffset() int32 {
 	return 0
 }
 
+func (m *Meta) GetCallerUser() string {
+	if m != nil {
+		return m.CallerUser
+	}
+	return ""
+}
+
+func (m *Meta) GetCallerHost() string {
+	if m != nil {
+		return m.CallerHost
+	}
+	return ""
+}
+
+func (m *Meta) GetFrameVersion() uint32 {
+	if m != nil {
+		return m.FrameVersion
+	}
+	return 0
+}
+
+// Hello to agree the frame version of a connection.
+type Hello struct {
+	Version uint32 `protobuf:"varint,1,opt,name=version" json:"version,omitempty"`
+}
+
+func (m *Hello) Reset()                    { *m = Hello{} }
+func (m *Hello) String() string            { return proto.CompactTextString(m) }
+func (*Hello) ProtoMessage()               {}
+func (*Hello) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *Hello) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+type Stats struct {
+	Accepted uint64 `protobuf:"varint,1,opt,name=accepted" json:"accepted,omitempty"`
+	Dropped  uint64 `protobuf:"varint,2,opt,name=dropped" json:"dropped,omitempty"`
+	Queued   uint32 `protobuf:"varint,3,opt,name=queued" json:"queued,omitempty"`
+	Capacity uint32 `protobuf:"varint,4,opt,name=capacity" json:"capacity,omitempty"`
+	Policy   string `protobuf:"bytes,5,opt,name=policy" json:"policy,omitempty"`
+	// actions denied by the rules.
+	Denied uint64 `protobuf:"varint,6,opt,name=denied" json:"denied,omitempty"`
+}
+
+func (m *Stats) Reset()                    { *m = Stats{} }
+func (m *Stats) String() string            { return proto.CompactTextString(m) }
+func (*Stats) ProtoMessage()               {}
+func (*Stats) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *Stats) GetAccepted() uint64 {
+	if m != nil {
+		return m.Accepted
+	}
+	return 0
+}
+
+func (m *Stats) GetDropped() uint64 {
+	if m != nil {
+		return m.Dropped
+	}
+	return 0
+}
+
+func (m *Stats) GetQueued() uint32 {
+	if m != nil {
+		return m.Queued
+	}
+	return 0
+}
+
+func (m *Stats) GetCapacity() uint32 {
+	if m != nil {
+		return m.Capacity
+	}
+	return 0
+}
+
+func (m *Stats) GetPolicy() string {
+	if m != nil {
+		return m.Policy
+	}
+	return ""
+}
+
+func (m *Stats) GetDenied() uint64 {
+	if m != nil {
+		return m.Denied
+	}
+	return 0
+}
+
 type AllActions struct {
 	Actions []*AllActions_Act `protobuf:"bytes,1,rep,name=actions" json:"actions,omitempty"`
 }
@@ -113,7 +233,7 @@ type AllActions struct {
 func (m *AllActions) Reset()                    { *m = AllActions{} }
 func (m *AllActions) String() string            { return proto.CompactTextString(m) }
 func (*AllActions) ProtoMessage()               {}
-func (*AllActions) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*AllActions) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
 func (m *AllActions) GetActions() []*AllActions_Act {
 	if m != nil {
@@ -131,7 +251,7 @@ type AllActions_Act struct {
 func (m *AllActions_Act) Reset()                    { *m = AllActions_Act{} }
 func (m *AllActions_Act) String() string            { return proto.CompactTextString(m) }
 func (*AllActions_Act) ProtoMessage()               {}
-func (*AllActions_Act) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1, 0} }
+func (*AllActions_Act) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3, 0} }
 
 func (m *AllActions_Act) GetTarget() string {
 	if m != nil {
@@ -154,6 +274,166 @@ func (m *AllActions_Act) GetLast() uint32 {
 	return 0
 }
 
+// TimedAction an action happened at a time given by the client.
+type TimedAction struct {
+	Target string `protobuf:"bytes,1,opt,name=target" json:"target,omitempty"`
+	// unixtime when the action happened, 0 for now.
+	Ts uint32 `protobuf:"varint,2,opt,name=ts" json:"ts,omitempty"`
+	// stored aside of db, in the metadata file of the service.
+	Metadata map[string]string `protobuf:"bytes,3,rep,name=metadata" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+}
+
+func (m *TimedAction) Reset()                    { *m = TimedAction{} }
+func (m *TimedAction) String() string            { return proto.CompactTextString(m) }
+func (*TimedAction) ProtoMessage()               {}
+func (*TimedAction) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *TimedAction) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *TimedAction) GetTs() uint32 {
+	if m != nil {
+		return m.Ts
+	}
+	return 0
+}
+
+func (m *TimedAction) GetMetadata() map[string]string {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+// Ack replied after an action is written.
+type Ack struct {
+	Ts uint32 `protobuf:"varint,1,opt,name=ts" json:"ts,omitempty"`
+}
+
+func (m *Ack) Reset()                    { *m = Ack{} }
+func (m *Ack) String() string            { return proto.CompactTextString(m) }
+func (*Ack) ProtoMessage()               {}
+func (*Ack) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *Ack) GetTs() uint32 {
+	if m != nil {
+		return m.Ts
+	}
+	return 0
+}
+
+// ActionBatch of at most the queue capacity of actions, see Stats.capacity.
+type ActionBatch struct {
+	Actions []*TimedAction `protobuf:"bytes,1,rep,name=actions" json:"actions,omitempty"`
+	// reply after the actions are written.
+	Ack bool `protobuf:"varint,2,opt,name=ack" json:"ack,omitempty"`
+}
+
+func (m *ActionBatch) Reset()                    { *m = ActionBatch{} }
+func (m *ActionBatch) String() string            { return proto.CompactTextString(m) }
+func (*ActionBatch) ProtoMessage()               {}
+func (*ActionBatch) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *ActionBatch) GetActions() []*TimedAction {
+	if m != nil {
+		return m.Actions
+	}
+	return nil
+}
+
+func (m *ActionBatch) GetAck() bool {
+	if m != nil {
+		return m.Ack
+	}
+	return false
+}
+
+// BatchResult has one item for each action of the batch, in order.
+type BatchResult struct {
+	Items    []*BatchResult_Item `protobuf:"bytes,1,rep,name=items" json:"items,omitempty"`
+	Accepted uint32              `protobuf:"varint,2,opt,name=accepted" json:"accepted,omitempty"`
+	Rejected uint32              `protobuf:"varint,3,opt,name=rejected" json:"rejected,omitempty"`
+}
+
+func (m *BatchResult) Reset()                    { *m = BatchResult{} }
+func (m *BatchResult) String() string            { return proto.CompactTextString(m) }
+func (*BatchResult) ProtoMessage()               {}
+func (*BatchResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *BatchResult) GetItems() []*BatchResult_Item {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+func (m *BatchResult) GetAccepted() uint32 {
+	if m != nil {
+		return m.Accepted
+	}
+	return 0
+}
+
+func (m *BatchResult) GetRejected() uint32 {
+	if m != nil {
+		return m.Rejected
+	}
+	return 0
+}
+
+type BatchResult_Item struct {
+	Accepted bool   `protobuf:"varint,1,opt,name=accepted" json:"accepted,omitempty"`
+	Error    string `protobuf:"bytes,2,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *BatchResult_Item) Reset()                    { *m = BatchResult_Item{} }
+func (m *BatchResult_Item) String() string            { return proto.CompactTextString(m) }
+func (*BatchResult_Item) ProtoMessage()               {}
+func (*BatchResult_Item) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7, 0} }
+
+func (m *BatchResult_Item) GetAccepted() bool {
+	if m != nil {
+		return m.Accepted
+	}
+	return false
+}
+
+func (m *BatchResult_Item) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+// Filter to select targets, empty fields match all.
+type Filter struct {
+	Glob  string `protobuf:"bytes,1,opt,name=glob" json:"glob,omitempty"`
+	Regex string `protobuf:"bytes,2,opt,name=regex" json:"regex,omitempty"`
+}
+
+func (m *Filter) Reset()                    { *m = Filter{} }
+func (m *Filter) String() string            { return proto.CompactTextString(m) }
+func (*Filter) ProtoMessage()               {}
+func (*Filter) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *Filter) GetGlob() string {
+	if m != nil {
+		return m.Glob
+	}
+	return ""
+}
+
+func (m *Filter) GetRegex() string {
+	if m != nil {
+		return m.Regex
+	}
+	return ""
+}
+
 type Targets struct {
 	Target []string `protobuf:"bytes,1,rep,name=target" json:"target,omitempty"`
 }
@@ -161,7 +441,7 @@ type Targets struct {
 func (m *Targets) Reset()                    { *m = Targets{} }
 func (m *Targets) String() string            { return proto.CompactTextString(m) }
 func (*Targets) ProtoMessage()               {}
-func (*Targets) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*Targets) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
 
 func (m *Targets) GetTarget() []string {
 	if m != nil {
@@ -174,12 +454,18 @@ type SlotRange struct {
 	Target string `protobuf:"bytes,1,opt,name=target" json:"target,omitempty"`
 	Start  uint32 `protobuf:"varint,2,opt,name=start" json:"start,omitempty"`
 	End    uint32 `protobuf:"varint,3,opt,name=end" json:"end,omitempty"`
+	// slots of a page, 0 for all.
+	Limit uint32 `protobuf:"varint,4,opt,name=limit" json:"limit,omitempty"`
+	// next_page_token of the previous page.
+	PageToken []byte `protobuf:"bytes,5,opt,name=page_token,json=pageToken" json:"page_token,omitempty"`
+	// with a filter, the slots of every matched target, target can be empty.
+	Filter *Filter `protobuf:"bytes,6,opt,name=filter" json:"filter,omitempty"`
 }
 
 func (m *SlotRange) Reset()                    { *m = SlotRange{} }
 func (m *SlotRange) String() string            { return proto.CompactTextString(m) }
 func (*SlotRange) ProtoMessage()               {}
-func (*SlotRange) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*SlotRange) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
 
 func (m *SlotRange) GetTarget() string {
 	if m != nil {
@@ -202,15 +488,38 @@ func (m *SlotRange) GetEnd() uint32 {
 	return 0
 }
 
+func (m *SlotRange) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *SlotRange) GetPageToken() []byte {
+	if m != nil {
+		return m.PageToken
+	}
+	return nil
+}
+
+func (m *SlotRange) GetFilter() *Filter {
+	if m != nil {
+		return m.Filter
+	}
+	return nil
+}
+
 type Slot struct {
 	Start uint32 `protobuf:"varint,1,opt,name=start" json:"start,omitempty"`
 	Slot  uint32 `protobuf:"varint,2,opt,name=slot" json:"slot,omitempty"`
+	// set when the slots are queried by a filter.
+	Target string `protobuf:"bytes,3,opt,name=target" json:"target,omitempty"`
 }
 
 func (m *Slot) Reset()                    { *m = Slot{} }
 func (m *Slot) String() string            { return proto.CompactTextString(m) }
 func (*Slot) ProtoMessage()               {}
-func (*Slot) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*Slot) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
 
 func (m *Slot) GetStart() uint32 {
 	if m != nil {
@@ -226,14 +535,23 @@ func (m *Slot) GetSlot() uint32 {
 	return 0
 }
 
+func (m *Slot) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
 type Slots struct {
 	Slots []*Slot `protobuf:"bytes,1,rep,name=slots" json:"slots,omitempty"`
+	// empty on the last page.
+	NextPageToken []byte `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken" json:"next_page_token,omitempty"`
 }
 
 func (m *Slots) Reset()                    { *m = Slots{} }
 func (m *Slots) String() string            { return proto.CompactTextString(m) }
 func (*Slots) ProtoMessage()               {}
-func (*Slots) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (*Slots) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
 
 func (m *Slots) GetSlots() []*Slot {
 	if m != nil {
@@ -242,6 +560,497 @@ func (m *Slots) GetSlots() []*Slot {
 	return nil
 }
 
+func (m *Slots) GetNextPageToken() []byte {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return nil
+}
+
+type ReportRequest struct {
+	// targets to report, empty for all.
+	Targets []string `protobuf:"bytes,1,rep,name=targets" json:"targets,omitempty"`
+	Start   uint32   `protobuf:"varint,2,opt,name=start" json:"start,omitempty"`
+	End     uint32   `protobuf:"varint,3,opt,name=end" json:"end,omitempty"`
+	// hour, day, week or month, empty for day.
+	Bucket string `protobuf:"bytes,4,opt,name=bucket" json:"bucket,omitempty"`
+	// levels of the targets to roll up to, 0 for the whole target.
+	Depth uint32 `protobuf:"varint,5,opt,name=depth" json:"depth,omitempty"`
+	// only the targets at or below the prefix.
+	Prefix string `protobuf:"bytes,6,opt,name=prefix" json:"prefix,omitempty"`
+}
+
+func (m *ReportRequest) Reset()                    { *m = ReportRequest{} }
+func (m *ReportRequest) String() string            { return proto.CompactTextString(m) }
+func (*ReportRequest) ProtoMessage()               {}
+func (*ReportRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *ReportRequest) GetTargets() []string {
+	if m != nil {
+		return m.Targets
+	}
+	return nil
+}
+
+func (m *ReportRequest) GetStart() uint32 {
+	if m != nil {
+		return m.Start
+	}
+	return 0
+}
+
+func (m *ReportRequest) GetEnd() uint32 {
+	if m != nil {
+		return m.End
+	}
+	return 0
+}
+
+func (m *ReportRequest) GetBucket() string {
+	if m != nil {
+		return m.Bucket
+	}
+	return ""
+}
+
+func (m *ReportRequest) GetDepth() uint32 {
+	if m != nil {
+		return m.Depth
+	}
+	return 0
+}
+
+func (m *ReportRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+type Report struct {
+	// sorted by target, then bucket.
+	Rows []*Report_Row `protobuf:"bytes,1,rep,name=rows" json:"rows,omitempty"`
+	// zone of the buckets, seconds east of UTC.
+	ZoneOffset int32 `protobuf:"varint,2,opt,name=zone_offset,json=zoneOffset" json:"zone_offset,omitempty"`
+}
+
+func (m *Report) Reset()                    { *m = Report{} }
+func (m *Report) String() string            { return proto.CompactTextString(m) }
+func (*Report) ProtoMessage()               {}
+func (*Report) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *Report) GetRows() []*Report_Row {
+	if m != nil {
+		return m.Rows
+	}
+	return nil
+}
+
+func (m *Report) GetZoneOffset() int32 {
+	if m != nil {
+		return m.ZoneOffset
+	}
+	return 0
+}
+
+type Report_Row struct {
+	Target string `protobuf:"bytes,1,opt,name=target" json:"target,omitempty"`
+	// unixtime of the bucket start.
+	Bucket  uint32 `protobuf:"varint,2,opt,name=bucket" json:"bucket,omitempty"`
+	Seconds uint64 `protobuf:"varint,3,opt,name=seconds" json:"seconds,omitempty"`
+}
+
+func (m *Report_Row) Reset()                    { *m = Report_Row{} }
+func (m *Report_Row) String() string            { return proto.CompactTextString(m) }
+func (*Report_Row) ProtoMessage()               {}
+func (*Report_Row) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14, 0} }
+
+func (m *Report_Row) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *Report_Row) GetBucket() uint32 {
+	if m != nil {
+		return m.Bucket
+	}
+	return 0
+}
+
+func (m *Report_Row) GetSeconds() uint64 {
+	if m != nil {
+		return m.Seconds
+	}
+	return 0
+}
+
+type TreeRequest struct {
+	// root of the tree, empty for all targets.
+	Prefix string `protobuf:"bytes,1,opt,name=prefix" json:"prefix,omitempty"`
+	// levels below the root, 0 for all.
+	Depth uint32 `protobuf:"varint,2,opt,name=depth" json:"depth,omitempty"`
+	Start uint32 `protobuf:"varint,3,opt,name=start" json:"start,omitempty"`
+	End   uint32 `protobuf:"varint,4,opt,name=end" json:"end,omitempty"`
+}
+
+func (m *TreeRequest) Reset()                    { *m = TreeRequest{} }
+func (m *TreeRequest) String() string            { return proto.CompactTextString(m) }
+func (*TreeRequest) ProtoMessage()               {}
+func (*TreeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *TreeRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *TreeRequest) GetDepth() uint32 {
+	if m != nil {
+		return m.Depth
+	}
+	return 0
+}
+
+func (m *TreeRequest) GetStart() uint32 {
+	if m != nil {
+		return m.Start
+	}
+	return 0
+}
+
+func (m *TreeRequest) GetEnd() uint32 {
+	if m != nil {
+		return m.End
+	}
+	return 0
+}
+
+// TargetNode a level of targets, seconds include the levels below.
+type TargetNode struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// the levels from the top joined by the separator.
+	Path     string        `protobuf:"bytes,2,opt,name=path" json:"path,omitempty"`
+	Seconds  uint64        `protobuf:"varint,3,opt,name=seconds" json:"seconds,omitempty"`
+	Children []*TargetNode `protobuf:"bytes,4,rep,name=children" json:"children,omitempty"`
+}
+
+func (m *TargetNode) Reset()                    { *m = TargetNode{} }
+func (m *TargetNode) String() string            { return proto.CompactTextString(m) }
+func (*TargetNode) ProtoMessage()               {}
+func (*TargetNode) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *TargetNode) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *TargetNode) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *TargetNode) GetSeconds() uint64 {
+	if m != nil {
+		return m.Seconds
+	}
+	return 0
+}
+
+func (m *TargetNode) GetChildren() []*TargetNode {
+	if m != nil {
+		return m.Children
+	}
+	return nil
+}
+
+type RulesRequest struct {
+	// load the rules file again before listing.
+	Reload bool `protobuf:"varint,1,opt,name=reload" json:"reload,omitempty"`
+}
+
+func (m *RulesRequest) Reset()                    { *m = RulesRequest{} }
+func (m *RulesRequest) String() string            { return proto.CompactTextString(m) }
+func (*RulesRequest) ProtoMessage()               {}
+func (*RulesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *RulesRequest) GetReload() bool {
+	if m != nil {
+		return m.Reload
+	}
+	return false
+}
+
+// Rules to allow or deny targets, the last rule matched wins.
+type Rules struct {
+	Rules []*Rules_Rule `protobuf:"bytes,1,rep,name=rules" json:"rules,omitempty"`
+	File  string        `protobuf:"bytes,2,opt,name=file" json:"file,omitempty"`
+}
+
+func (m *Rules) Reset()                    { *m = Rules{} }
+func (m *Rules) String() string            { return proto.CompactTextString(m) }
+func (*Rules) ProtoMessage()               {}
+func (*Rules) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *Rules) GetRules() []*Rules_Rule {
+	if m != nil {
+		return m.Rules
+	}
+	return nil
+}
+
+func (m *Rules) GetFile() string {
+	if m != nil {
+		return m.File
+	}
+	return ""
+}
+
+type Rules_Rule struct {
+	Action  string `protobuf:"bytes,1,opt,name=action" json:"action,omitempty"`
+	Kind    string `protobuf:"bytes,2,opt,name=kind" json:"kind,omitempty"`
+	Pattern string `protobuf:"bytes,3,opt,name=pattern" json:"pattern,omitempty"`
+}
+
+func (m *Rules_Rule) Reset()                    { *m = Rules_Rule{} }
+func (m *Rules_Rule) String() string            { return proto.CompactTextString(m) }
+func (*Rules_Rule) ProtoMessage()               {}
+func (*Rules_Rule) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18, 0} }
+
+func (m *Rules_Rule) GetAction() string {
+	if m != nil {
+		return m.Action
+	}
+	return ""
+}
+
+func (m *Rules_Rule) GetKind() string {
+	if m != nil {
+		return m.Kind
+	}
+	return ""
+}
+
+func (m *Rules_Rule) GetPattern() string {
+	if m != nil {
+		return m.Pattern
+	}
+	return ""
+}
+
+type ExpiryRequest struct {
+	// seconds between two checks to set, 0 to keep.
+	Interval uint32 `protobuf:"varint,1,opt,name=interval" json:"interval,omitempty"`
+}
+
+func (m *ExpiryRequest) Reset()                    { *m = ExpiryRequest{} }
+func (m *ExpiryRequest) String() string            { return proto.CompactTextString(m) }
+func (*ExpiryRequest) ProtoMessage()               {}
+func (*ExpiryRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *ExpiryRequest) GetInterval() uint32 {
+	if m != nil {
+		return m.Interval
+	}
+	return 0
+}
+
+// Expiry the checks of expired actions. How long an action stays open
+// without new action is tdb's own timeout, it can't be changed.
+type Expiry struct {
+	// seconds between two checks.
+	Interval uint32 `protobuf:"varint,1,opt,name=interval" json:"interval,omitempty"`
+	// unixtime of the last check, 0 if none yet.
+	LastCheck uint32 `protobuf:"varint,2,opt,name=last_check,json=lastCheck" json:"last_check,omitempty"`
+	// actions closed by the last check.
+	LastExpired uint32 `protobuf:"varint,3,opt,name=last_expired,json=lastExpired" json:"last_expired,omitempty"`
+	// milliseconds the last check took.
+	LastDuration uint32 `protobuf:"varint,4,opt,name=last_duration,json=lastDuration" json:"last_duration,omitempty"`
+	Checks       uint64 `protobuf:"varint,5,opt,name=checks" json:"checks,omitempty"`
+}
+
+func (m *Expiry) Reset()                    { *m = Expiry{} }
+func (m *Expiry) String() string            { return proto.CompactTextString(m) }
+func (*Expiry) ProtoMessage()               {}
+func (*Expiry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+func (m *Expiry) GetInterval() uint32 {
+	if m != nil {
+		return m.Interval
+	}
+	return 0
+}
+
+func (m *Expiry) GetLastCheck() uint32 {
+	if m != nil {
+		return m.LastCheck
+	}
+	return 0
+}
+
+func (m *Expiry) GetLastExpired() uint32 {
+	if m != nil {
+		return m.LastExpired
+	}
+	return 0
+}
+
+func (m *Expiry) GetLastDuration() uint32 {
+	if m != nil {
+		return m.LastDuration
+	}
+	return 0
+}
+
+func (m *Expiry) GetChecks() uint64 {
+	if m != nil {
+		return m.Checks
+	}
+	return 0
+}
+
+// ReloadResult the settings changed by a reload.
+type ReloadResult struct {
+	Changed []string `protobuf:"bytes,1,rep,name=changed" json:"changed,omitempty"`
+	// settings changed in the config but kept until a restart.
+	Restart []string `protobuf:"bytes,2,rep,name=restart" json:"restart,omitempty"`
+}
+
+func (m *ReloadResult) Reset()                    { *m = ReloadResult{} }
+func (m *ReloadResult) String() string            { return proto.CompactTextString(m) }
+func (*ReloadResult) ProtoMessage()               {}
+func (*ReloadResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+func (m *ReloadResult) GetChanged() []string {
+	if m != nil {
+		return m.Changed
+	}
+	return nil
+}
+
+func (m *ReloadResult) GetRestart() []string {
+	if m != nil {
+		return m.Restart
+	}
+	return nil
+}
+
+type AuthRequest struct {
+	Token string `protobuf:"bytes,1,opt,name=token" json:"token,omitempty"`
+	// host of the client, to tag its actions.
+	Host string `protobuf:"bytes,2,opt,name=host" json:"host,omitempty"`
+}
+
+func (m *AuthRequest) Reset()                    { *m = AuthRequest{} }
+func (m *AuthRequest) String() string            { return proto.CompactTextString(m) }
+func (*AuthRequest) ProtoMessage()               {}
+func (*AuthRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *AuthRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *AuthRequest) GetHost() string {
+	if m != nil {
+		return m.Host
+	}
+	return ""
+}
+
+type AuthResult struct {
+	// the permission granted, open 0, read 1, write 2 or admin 3.
+	Permission uint32 `protobuf:"varint,1,opt,name=permission" json:"permission,omitempty"`
+	// user of the token, empty for the token of the token file.
+	User string `protobuf:"bytes,2,opt,name=user" json:"user,omitempty"`
+	Host string `protobuf:"bytes,3,opt,name=host" json:"host,omitempty"`
+}
+
+func (m *AuthResult) Reset()                    { *m = AuthResult{} }
+func (m *AuthResult) String() string            { return proto.CompactTextString(m) }
+func (*AuthResult) ProtoMessage()               {}
+func (*AuthResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+
+func (m *AuthResult) GetPermission() uint32 {
+	if m != nil {
+		return m.Permission
+	}
+	return 0
+}
+
+func (m *AuthResult) GetUser() string {
+	if m != nil {
+		return m.User
+	}
+	return ""
+}
+
+func (m *AuthResult) GetHost() string {
+	if m != nil {
+		return m.Host
+	}
+	return ""
+}
+
+// AdminRequest of an operation: shutdown, restart-listener, flush or
+// reload.
+type AdminRequest struct {
+	Op string `protobuf:"bytes,1,opt,name=op" json:"op,omitempty"`
+}
+
+func (m *AdminRequest) Reset()                    { *m = AdminRequest{} }
+func (m *AdminRequest) String() string            { return proto.CompactTextString(m) }
+func (*AdminRequest) ProtoMessage()               {}
+func (*AdminRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
+func (m *AdminRequest) GetOp() string {
+	if m != nil {
+		return m.Op
+	}
+	return ""
+}
+
+type AdminResult struct {
+	Op string `protobuf:"bytes,1,opt,name=op" json:"op,omitempty"`
+	// actions written by flush.
+	Flushed uint32        `protobuf:"varint,2,opt,name=flushed" json:"flushed,omitempty"`
+	Reload  *ReloadResult `protobuf:"bytes,3,opt,name=reload" json:"reload,omitempty"`
+}
+
+func (m *AdminResult) Reset()                    { *m = AdminResult{} }
+func (m *AdminResult) String() string            { return proto.CompactTextString(m) }
+func (*AdminResult) ProtoMessage()               {}
+func (*AdminResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+
+func (m *AdminResult) GetOp() string {
+	if m != nil {
+		return m.Op
+	}
+	return ""
+}
+
+func (m *AdminResult) GetFlushed() uint32 {
+	if m != nil {
+		return m.Flushed
+	}
+	return 0
+}
+
+func (m *AdminResult) GetReload() *ReloadResult {
+	if m != nil {
+		return m.Reload
+	}
+	return nil
+}
+
 type ErrorMessage struct {
 	Message string `protobuf:"bytes,1,opt,name=message" json:"message,omitempty"`
 }
@@ -249,7 +1058,7 @@ type ErrorMessage struct {
 func (m *ErrorMessage) Reset()                    { *m = ErrorMessage{} }
 func (m *ErrorMessage) String() string            { return proto.CompactTextString(m) }
 func (*ErrorMessage) ProtoMessage()               {}
-func (*ErrorMessage) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+func (*ErrorMessage) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
 
 func (m *ErrorMessage) GetMessage() string {
 	if m != nil {
@@ -260,40 +1069,118 @@ func (m *ErrorMessage) GetMessage() string {
 
 func init() {
 	proto.RegisterType((*Meta)(nil), "service.Meta")
+	proto.RegisterType((*Hello)(nil), "service.Hello")
+	proto.RegisterType((*Stats)(nil), "service.Stats")
 	proto.RegisterType((*AllActions)(nil), "service.AllActions")
 	proto.RegisterType((*AllActions_Act)(nil), "service.AllActions.Act")
+	proto.RegisterType((*TimedAction)(nil), "service.TimedAction")
+	proto.RegisterType((*Ack)(nil), "service.Ack")
+	proto.RegisterType((*ActionBatch)(nil), "service.ActionBatch")
+	proto.RegisterType((*BatchResult)(nil), "service.BatchResult")
+	proto.RegisterType((*BatchResult_Item)(nil), "service.BatchResult.Item")
+	proto.RegisterType((*Filter)(nil), "service.Filter")
 	proto.RegisterType((*Targets)(nil), "service.Targets")
 	proto.RegisterType((*SlotRange)(nil), "service.SlotRange")
 	proto.RegisterType((*Slot)(nil), "service.Slot")
 	proto.RegisterType((*Slots)(nil), "service.Slots")
+	proto.RegisterType((*ReportRequest)(nil), "service.ReportRequest")
+	proto.RegisterType((*Report)(nil), "service.Report")
+	proto.RegisterType((*Report_Row)(nil), "service.Report.Row")
+	proto.RegisterType((*TreeRequest)(nil), "service.TreeRequest")
+	proto.RegisterType((*TargetNode)(nil), "service.TargetNode")
+	proto.RegisterType((*RulesRequest)(nil), "service.RulesRequest")
+	proto.RegisterType((*Rules)(nil), "service.Rules")
+	proto.RegisterType((*Rules_Rule)(nil), "service.Rules.Rule")
+	proto.RegisterType((*ExpiryRequest)(nil), "service.ExpiryRequest")
+	proto.RegisterType((*Expiry)(nil), "service.Expiry")
+	proto.RegisterType((*ReloadResult)(nil), "service.ReloadResult")
+	proto.RegisterType((*AuthRequest)(nil), "service.AuthRequest")
+	proto.RegisterType((*AuthResult)(nil), "service.AuthResult")
+	proto.RegisterType((*AdminRequest)(nil), "service.AdminRequest")
+	proto.RegisterType((*AdminResult)(nil), "service.AdminResult")
 	proto.RegisterType((*ErrorMessage)(nil), "service.ErrorMessage")
 }
 
 func init() { proto.RegisterFile("service/service.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 362 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x52, 0xcb, 0x6e, 0xdb, 0x30,
-	0x10, 0x04, 0x2d, 0xc9, 0xb2, 0xd6, 0x75, 0x61, 0x10, 0x7d, 0x10, 0xee, 0xa1, 0xaa, 0x7a, 0xd1,
-	0xa1, 0x70, 0xdb, 0xe4, 0x0b, 0x74, 0x08, 0x72, 0x08, 0x8c, 0x00, 0x4a, 0xee, 0x06, 0xa3, 0xac,
-	0x65, 0x03, 0xb2, 0x18, 0x70, 0x37, 0x3e, 0xe4, 0x0b, 0xf2, 0x61, 0xf9, 0xb0, 0x80, 0x94, 0x64,
-	0x23, 0xb9, 0xe5, 0xa4, 0x99, 0xd1, 0x70, 0x39, 0x1c, 0x2c, 0x7c, 0x25, 0xb4, 0x87, 0x5d, 0x85,
-	0x7f, 0xfb, 0xef, 0xf2, 0xc1, 0x1a, 0x36, 0x32, 0xee, 0x69, 0xf6, 0x22, 0x20, 0x5c, 0x21, 0x6b,
-	0xa9, 0x20, 0x3e, 0xa0, 0xa5, 0x9d, 0x69, 0x95, 0x48, 0x45, 0x3e, 0x2b, 0x07, 0x2a, 0xe7, 0x10,
-	0xb0, 0xae, 0xd5, 0x28, 0x15, 0x79, 0x52, 0x3a, 0x28, 0x7f, 0x40, 0x52, 0x59, 0xd4, 0x8c, 0x6b,
-	0xcd, 0x2a, 0xf0, 0xee, 0x49, 0x27, 0x14, 0x2c, 0x25, 0x84, 0x5b, 0x43, 0xac, 0x42, 0xef, 0xf7,
-	0x58, 0x2e, 0x60, 0xf2, 0x48, 0x68, 0x5b, 0xbd, 0x47, 0x15, 0x79, 0xfd, 0xc8, 0x9d, 0x5f, 0xdb,
-	0x6a, 0xab, 0xc6, 0x9d, 0xdf, 0x61, 0xf9, 0x19, 0x46, 0x86, 0x54, 0xec, 0x95, 0x91, 0x21, 0xf9,
-	0x13, 0xa6, 0x4f, 0xa6, 0xc5, 0xb5, 0xd9, 0x6c, 0x08, 0x59, 0x4d, 0x52, 0x91, 0x47, 0x25, 0x38,
-	0xe9, 0xda, 0x2b, 0xd9, 0xb3, 0x00, 0x28, 0x9a, 0xa6, 0xa8, 0x78, 0x67, 0x5a, 0x92, 0xff, 0x21,
-	0xd6, 0x1d, 0x54, 0x22, 0x0d, 0xf2, 0xe9, 0xd9, 0xf7, 0xe5, 0xf0, 0xfe, 0x93, 0x6b, 0x59, 0x54,
-	0x5c, 0x0e, 0xbe, 0xc5, 0x25, 0x04, 0x45, 0xc5, 0xf2, 0x1b, 0x8c, 0x59, 0xdb, 0x1a, 0xd9, 0xb7,
-	0x90, 0x94, 0x3d, 0x93, 0x5f, 0x20, 0x22, 0xd6, 0x96, 0x7d, 0x0d, 0xb3, 0xb2, 0x23, 0x2e, 0x7b,
-	0xa3, 0x69, 0xe8, 0xc0, 0xe3, 0xec, 0x17, 0xc4, 0xb7, 0xfe, 0x0c, 0xbd, 0x19, 0x16, 0x9c, 0x86,
-	0x65, 0x57, 0x90, 0xdc, 0x34, 0x86, 0x4b, 0xdd, 0xd6, 0xf8, 0xc1, 0x1b, 0xe7, 0x10, 0x60, 0x7b,
-	0xdf, 0x5f, 0xe8, 0x60, 0xf6, 0x0f, 0x42, 0x37, 0xec, 0xe4, 0x17, 0xef, 0x12, 0x52, 0x63, 0x86,
-	0x21, 0x1e, 0x67, 0x7f, 0x20, 0x72, 0x27, 0x48, 0xfe, 0x86, 0xc8, 0x09, 0x43, 0x49, 0xb3, 0x63,
-	0x49, 0x3e, 0x5d, 0xf7, 0x2f, 0xcb, 0xe1, 0xd3, 0x85, 0xb5, 0xc6, 0xae, 0x90, 0x48, 0xd7, 0xe8,
-	0x16, 0x65, 0xdf, 0xc1, 0x3e, 0xf0, 0x40, 0xef, 0xc6, 0x7e, 0xb7, 0xce, 0x5f, 0x03, 0x00, 0x00,
-	0xff, 0xff, 0xf8, 0x06, 0xa0, 0xe2, 0x74, 0x02, 0x00, 0x00,
+	// 1238 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x56, 0xd1, 0x8f, 0xdb, 0xc4,
+	0x13, 0x96, 0x13, 0xe7, 0x92, 0x4c, 0x92, 0xb6, 0xf2, 0xef, 0xda, 0x9f, 0x09, 0xa2, 0x5c, 0x5d,
+	0xa9, 0x3d, 0x84, 0x48, 0x45, 0x79, 0xa0, 0x02, 0x09, 0x29, 0x85, 0x83, 0x22, 0x51, 0x8a, 0xb6,
+	0x07, 0xaf, 0xd1, 0xd6, 0x9e, 0x24, 0xe6, 0x1c, 0xdb, 0xdd, 0xdd, 0x5c, 0xef, 0x78, 0xe1, 0x95,
+	0x3f, 0x00, 0x89, 0x67, 0xc4, 0x13, 0xe2, 0x05, 0xf1, 0x17, 0xa2, 0x99, 0xdd, 0x75, 0xdc, 0x6b,
+	0x0f, 0xc1, 0x4b, 0x32, 0xdf, 0xec, 0xec, 0xec, 0x7c, 0xdf, 0xee, 0xce, 0x1a, 0xae, 0x6b, 0x54,
+	0xa7, 0x79, 0x8a, 0xf7, 0xdc, 0xff, 0xac, 0x56, 0x95, 0xa9, 0xa2, 0xbe, 0x83, 0xc9, 0x1f, 0x1d,
+	0x08, 0x1f, 0xa3, 0x91, 0x51, 0x0c, 0xfd, 0x53, 0x54, 0x3a, 0xaf, 0xca, 0x38, 0x38, 0x08, 0x0e,
+	0x27, 0xc2, 0xc3, 0xe8, 0x1a, 0x74, 0x8d, 0x5c, 0xc5, 0x9d, 0x83, 0xe0, 0x70, 0x28, 0xc8, 0x8c,
+	0xde, 0x84, 0x61, 0xaa, 0x50, 0x1a, 0x5c, 0x48, 0x13, 0x77, 0x39, 0x7a, 0x60, 0x1d, 0x73, 0x13,
+	0x45, 0x10, 0xae, 0x2b, 0x6d, 0xe2, 0x90, 0xe3, 0xd9, 0x8e, 0xa6, 0x30, 0xd8, 0x6a, 0x54, 0xa5,
+	0xdc, 0x60, 0xdc, 0x63, 0x7f, 0x83, 0x29, 0x5e, 0xaa, 0x74, 0x1d, 0xef, 0xd9, 0x78, 0xb2, 0xa3,
+	0x2b, 0xd0, 0xa9, 0x74, 0xdc, 0x67, 0x4f, 0xa7, 0xd2, 0xd1, 0xdb, 0x30, 0xfa, 0xa1, 0x2a, 0x71,
+	0x51, 0x2d, 0x97, 0x1a, 0x4d, 0x3c, 0x38, 0x08, 0x0e, 0x7b, 0x02, 0xc8, 0xf5, 0x84, 0x3d, 0x14,
+	0x90, 0xca, 0xa2, 0x40, 0xb5, 0xa0, 0xbc, 0xf1, 0x90, 0x67, 0x82, 0x75, 0x7d, 0xab, 0x51, 0xb5,
+	0x02, 0xb8, 0x38, 0x68, 0x07, 0x3c, 0xa2, 0x12, 0x6f, 0xc3, 0x64, 0xa9, 0xe4, 0x06, 0x17, 0x5e,
+	0x85, 0x11, 0xf3, 0x1a, 0xb3, 0xf3, 0x3b, 0xeb, 0x4b, 0x6e, 0x41, 0xef, 0x11, 0x16, 0x45, 0x75,
+	0xb9, 0x5a, 0xc9, 0xaf, 0x01, 0xf4, 0x9e, 0x1a, 0x69, 0x34, 0x91, 0x96, 0x69, 0x8a, 0xb5, 0xc1,
+	0x8c, 0x83, 0x42, 0xd1, 0x60, 0x9a, 0x9f, 0xa9, 0xaa, 0xae, 0x31, 0x63, 0x5d, 0x43, 0xe1, 0x61,
+	0x74, 0x03, 0xf6, 0x9e, 0x6f, 0x71, 0x8b, 0x99, 0x13, 0xd6, 0x21, 0xca, 0x96, 0xca, 0x5a, 0xa6,
+	0xb9, 0x39, 0x8f, 0x43, 0x27, 0xb9, 0xc3, 0x34, 0xa7, 0xae, 0x8a, 0x3c, 0x3d, 0x77, 0xe2, 0x3a,
+	0x44, 0xfe, 0x0c, 0xcb, 0x1c, 0x33, 0x16, 0x37, 0x14, 0x0e, 0x25, 0x3f, 0x05, 0x00, 0xf3, 0xa2,
+	0x98, 0xa7, 0x26, 0xaf, 0x4a, 0x1d, 0xbd, 0x0f, 0x7d, 0x69, 0xcd, 0x38, 0x38, 0xe8, 0x1e, 0x8e,
+	0xee, 0xff, 0x7f, 0xe6, 0x4f, 0xcb, 0x2e, 0x6a, 0x36, 0x4f, 0x8d, 0xf0, 0x71, 0xd3, 0x2f, 0xa0,
+	0x3b, 0x4f, 0x0d, 0x2d, 0x60, 0xa4, 0x5a, 0xa1, 0x61, 0x82, 0x43, 0xe1, 0x50, 0xb4, 0x0f, 0x3d,
+	0x6d, 0xa4, 0x32, 0x4c, 0x6e, 0x22, 0x2c, 0xa0, 0x9d, 0x2e, 0xa4, 0xf6, 0x27, 0x86, 0xed, 0xe4,
+	0xaf, 0x00, 0x46, 0xc7, 0xf9, 0x06, 0x33, 0xbb, 0xcc, 0xa5, 0x19, 0xaf, 0x40, 0xc7, 0x68, 0x97,
+	0xae, 0x63, 0x74, 0xf4, 0x09, 0x0c, 0x36, 0x68, 0x64, 0x26, 0x8d, 0x8c, 0xbb, 0x5c, 0x74, 0xd2,
+	0x14, 0xdd, 0xca, 0x37, 0x7b, 0xec, 0x82, 0x8e, 0x4a, 0xa3, 0xce, 0x45, 0x33, 0x67, 0xfa, 0x31,
+	0x4c, 0x5e, 0x1a, 0xa2, 0x53, 0x7e, 0x82, 0xe7, 0x6e, 0x55, 0x32, 0x89, 0xc4, 0xa9, 0x2c, 0xb6,
+	0xe8, 0x4e, 0xbe, 0x05, 0x1f, 0x75, 0x1e, 0x04, 0xc9, 0x75, 0x62, 0x7f, 0xe2, 0x6a, 0x0a, 0x7c,
+	0x4d, 0xc9, 0x13, 0x18, 0xd9, 0x55, 0x1f, 0x4a, 0x93, 0xae, 0xa3, 0xd9, 0x45, 0x59, 0xf7, 0x5f,
+	0x57, 0x61, 0xa3, 0x29, 0x55, 0x20, 0xd3, 0x13, 0x5e, 0x6d, 0x20, 0xc8, 0x4c, 0xfe, 0x0c, 0x60,
+	0xc4, 0xb9, 0x04, 0xea, 0x6d, 0x61, 0xa2, 0x7b, 0xd0, 0xcb, 0x0d, 0x6e, 0x7c, 0xbe, 0x37, 0x9a,
+	0x7c, 0xad, 0xa0, 0xd9, 0x97, 0x06, 0x37, 0xc2, 0xc6, 0xbd, 0x74, 0x04, 0xad, 0x76, 0x0d, 0xa6,
+	0x31, 0x85, 0xdf, 0x63, 0x6a, 0x9a, 0xa3, 0xd6, 0xe0, 0xe9, 0x03, 0x08, 0x29, 0xcd, 0x2b, 0x47,
+	0x78, 0xd0, 0x9a, 0xbf, 0x0f, 0x3d, 0x54, 0xaa, 0x52, 0x5e, 0x1e, 0x06, 0xc9, 0x7d, 0xd8, 0xfb,
+	0x3c, 0x2f, 0x0c, 0x2a, 0xda, 0xed, 0x55, 0x51, 0x3d, 0x73, 0x8a, 0xb2, 0x4d, 0x73, 0x14, 0xae,
+	0xf0, 0xcc, 0xcf, 0x61, 0x90, 0xdc, 0x82, 0xfe, 0x31, 0xef, 0xb2, 0x7e, 0x69, 0xfb, 0xbb, 0xbb,
+	0xed, 0x4f, 0x7e, 0x0f, 0x60, 0xf8, 0xb4, 0xa8, 0x8c, 0x90, 0xe5, 0x0a, 0xff, 0xe3, 0xb1, 0xbb,
+	0x06, 0x5d, 0x2c, 0x3d, 0x47, 0x32, 0x29, 0xae, 0xc8, 0x37, 0xb9, 0x71, 0x17, 0xc9, 0x82, 0xe8,
+	0x2d, 0x80, 0x5a, 0xae, 0x70, 0x61, 0xaa, 0x13, 0x2c, 0xf9, 0x26, 0x8d, 0xc5, 0x90, 0x3c, 0xc7,
+	0xe4, 0x88, 0xee, 0xc2, 0xde, 0x92, 0x99, 0xf1, 0x65, 0x1a, 0xdd, 0xbf, 0xda, 0xa8, 0x6f, 0x09,
+	0x0b, 0x37, 0x9c, 0x3c, 0x82, 0x90, 0x4a, 0xdd, 0x55, 0x13, 0x5c, 0xb8, 0x04, 0xba, 0xa8, 0x7c,
+	0x89, 0x6c, 0xb7, 0xf8, 0x74, 0xdb, 0x7c, 0x92, 0x63, 0xe8, 0x51, 0x26, 0x1d, 0xdd, 0x86, 0x1e,
+	0x05, 0xfa, 0x8d, 0x9f, 0x34, 0x4b, 0xb3, 0x26, 0x76, 0x2c, 0xba, 0x03, 0x57, 0x4b, 0x3c, 0x33,
+	0x8b, 0x16, 0x89, 0x0e, 0x93, 0x98, 0x90, 0xfb, 0x1b, 0x4f, 0x24, 0xf9, 0x25, 0x80, 0x89, 0xc0,
+	0xba, 0x52, 0x46, 0xe0, 0xf3, 0x2d, 0x6a, 0x43, 0xdd, 0xc8, 0xae, 0xa8, 0x9d, 0xec, 0x1e, 0xfe,
+	0x6b, 0x45, 0x6f, 0xc0, 0xde, 0xb3, 0x6d, 0x7a, 0x82, 0xbe, 0xed, 0x3b, 0x44, 0xf3, 0x33, 0xac,
+	0xcd, 0x9a, 0xe5, 0x9c, 0x08, 0x0b, 0xb8, 0x5f, 0x29, 0x5c, 0xe6, 0x67, 0xae, 0xe9, 0x3b, 0x94,
+	0xfc, 0x16, 0xc0, 0x9e, 0xad, 0x2c, 0xba, 0x0b, 0xa1, 0xaa, 0x5e, 0x78, 0xc2, 0xff, 0x6b, 0x08,
+	0xdb, 0xe1, 0x99, 0xa8, 0x5e, 0x08, 0x0e, 0xb8, 0xf8, 0x34, 0x74, 0x2e, 0x3e, 0x0d, 0xd3, 0x27,
+	0xd0, 0x15, 0xd5, 0x8b, 0x4b, 0xcf, 0xcc, 0xae, 0x72, 0x4b, 0xd1, 0x57, 0x1e, 0x43, 0x5f, 0x63,
+	0x5a, 0x95, 0x99, 0x66, 0x9e, 0xa1, 0xf0, 0x30, 0x49, 0x61, 0x74, 0xac, 0x10, 0xbd, 0x78, 0x3b,
+	0x32, 0x41, 0x9b, 0xcc, 0x8e, 0x7a, 0xa7, 0x4d, 0xbd, 0x11, 0xb4, 0xfb, 0x1a, 0x41, 0xc3, 0x46,
+	0xd0, 0xe4, 0x47, 0x00, 0x7b, 0x27, 0xbe, 0xae, 0x32, 0x7e, 0x23, 0xf9, 0xed, 0x74, 0x77, 0xc9,
+	0xbf, 0x9b, 0xb5, 0x74, 0xe9, 0x87, 0x82, 0xed, 0xcb, 0x8b, 0x8e, 0xee, 0xc1, 0x20, 0x5d, 0xe7,
+	0x45, 0xa6, 0xb0, 0x8c, 0xc3, 0x0b, 0x9a, 0xee, 0x16, 0x12, 0x4d, 0x50, 0x72, 0x07, 0xc6, 0x62,
+	0x5b, 0xa0, 0x6e, 0xd1, 0x54, 0x58, 0x54, 0xd2, 0x37, 0x02, 0x87, 0x92, 0x9f, 0x03, 0xe8, 0x71,
+	0x60, 0xf4, 0x0e, 0xf4, 0x14, 0x19, 0xaf, 0xee, 0x19, 0x79, 0xf9, 0x57, 0xd8, 0x08, 0xaa, 0x7d,
+	0x99, 0x17, 0xbe, 0xb3, 0xb2, 0x3d, 0xfd, 0x0a, 0x42, 0x0a, 0xa1, 0x85, 0x6c, 0x47, 0xf4, 0x7a,
+	0x5a, 0x44, 0x73, 0x4e, 0xf2, 0x32, 0xf3, 0x73, 0xc8, 0x26, 0xbe, 0xb5, 0x34, 0x06, 0x55, 0xe9,
+	0x6e, 0x8e, 0x87, 0xc9, 0xbb, 0x30, 0x39, 0x3a, 0xab, 0x73, 0x75, 0xee, 0xeb, 0x9f, 0xc2, 0x20,
+	0x2f, 0x0d, 0xaa, 0x53, 0x59, 0xb8, 0x0b, 0xd9, 0x60, 0x3e, 0x77, 0x36, 0xfa, 0x9f, 0xc2, 0xa8,
+	0x41, 0xd0, 0x9b, 0xb5, 0x48, 0xd7, 0xe8, 0xfa, 0xf4, 0x44, 0x0c, 0xc9, 0xf3, 0x29, 0x39, 0xa2,
+	0x5b, 0x30, 0xe6, 0x61, 0xa4, 0x4c, 0x4d, 0x53, 0x1d, 0x91, 0xef, 0xc8, 0xba, 0xe8, 0x23, 0x83,
+	0x43, 0xb2, 0xad, 0x92, 0x4c, 0xd1, 0xee, 0x38, 0xcf, 0xfb, 0xcc, 0xf9, 0x48, 0x00, 0x5e, 0x41,
+	0xf3, 0xa5, 0x09, 0x85, 0x43, 0xc9, 0x43, 0x18, 0x0b, 0xd6, 0xdc, 0xbd, 0x06, 0x31, 0xf4, 0xd3,
+	0x35, 0xf5, 0xc3, 0xcc, 0xdf, 0x5a, 0x07, 0x69, 0x44, 0xa1, 0xbf, 0xb7, 0x3c, 0xe2, 0x60, 0xf2,
+	0x21, 0x8c, 0xe6, 0x5b, 0xb3, 0xf6, 0xa2, 0xec, 0x43, 0xcf, 0x36, 0x0a, 0x2b, 0xb5, 0x05, 0xcd,
+	0x17, 0x5c, 0x67, 0xf7, 0x05, 0x97, 0x1c, 0x03, 0xd8, 0x89, 0xbc, 0xf4, 0x4d, 0x80, 0x1a, 0xd5,
+	0x26, 0xd7, 0xad, 0x2f, 0xa0, 0x96, 0x87, 0x32, 0xf0, 0x77, 0x98, 0xcb, 0x40, 0x76, 0x93, 0xb5,
+	0xdb, 0xca, 0x7a, 0x13, 0xc6, 0xf3, 0x6c, 0x93, 0x97, 0xbe, 0x1e, 0xfa, 0xee, 0xab, 0x5d, 0x31,
+	0x9d, 0xaa, 0x4e, 0x96, 0x30, 0x72, 0xe3, 0xbc, 0xec, 0x85, 0x61, 0xe2, 0xb9, 0x2c, 0xb6, 0x7a,
+	0xdd, 0xbc, 0x6e, 0x1e, 0x46, 0xef, 0x35, 0xa7, 0xb5, 0xcb, 0xcd, 0xfa, 0x7a, 0xab, 0x81, 0xec,
+	0x24, 0x6c, 0x0e, 0xf1, 0x21, 0x8c, 0x8f, 0xe8, 0xf9, 0x7a, 0x8c, 0x5a, 0xcb, 0x15, 0x52, 0xe2,
+	0x8d, 0x35, 0xdd, 0x6a, 0x1e, 0x3e, 0xdb, 0xe3, 0xef, 0xe7, 0x0f, 0xfe, 0x1e, 0x00, 0xe0, 0x64,
+	0x47, 0xe9, 0x58, 0x0b, 0x00, 0x00,
 }
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+)
+
+const (
+	// slots of a chunk when streaming without limit
+	defaultChunkSlots = 1000
+	pageTokenBytes    = 12
+
+	// seconds of the first window of slots queried from db. The window
+	// doubles while windows hold less than a page, and halves when one holds
+	// more than two pages.
+	defaultSlotWindow = 24 * 3600
+	minSlotWindow     = 3600
+	maxSlotWindow     = 1 << 30
+)
+
+// ErrPageToken the page token can't be decoded
+var ErrPageToken = errors.New("invalid page token")
+
+// encodePageToken to point at a slot: its start, how many slots with the same
+// start are already returned, and the window to query from there.
+func encodePageToken(start, skip, window uint32) []byte {
+	buf := make([]byte, pageTokenBytes)
+	binary.LittleEndian.PutUint32(buf, start)
+	binary.LittleEndian.PutUint32(buf[4:], skip)
+	binary.LittleEndian.PutUint32(buf[8:], window)
+	return buf
+}
+
+func decodePageToken(buf []byte) (uint32, uint32, uint32, error) {
+	if len(buf) != pageTokenBytes {
+		return 0, 0, 0, ErrPageToken
+	}
+	window := binary.LittleEndian.Uint32(buf[8:])
+	if window < minSlotWindow || window > maxSlotWindow {
+		return 0, 0, 0, ErrPageToken
+	}
+	return binary.LittleEndian.Uint32(buf), binary.LittleEndian.Uint32(buf[4:]), window, nil
+}
+
+// slotPager to read the slots of a range page by page. db is queried a window
+// of time at once, from the start of the page, so a page only loads the
+// windows it needs instead of the whole range.
+type slotPager struct {
+	// query the slots between start and end, sorted by start
+	query func(start, end uint32) ([]*Slot, error)
+
+	// start of the next page, and how many slots at it are already returned
+	start, skip uint32
+	// pos and end of the windows left to query, window their length
+	pos, end, window uint32
+	// toSkip slots at start to drop from the first window
+	toSkip uint32
+	done   bool
+	// buf the slots queried and not returned yet
+	buf []*Slot
+}
+
+// newSlotPager to read the slots of the range, of the targets in the
+// namespace prefix, from its page token.
+func newSlotPager(rang *SlotRange, prefix string) (*slotPager, error) {
+	query, err := slotQuery(rang, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return pageSlots(query, rang)
+}
+
+// pageSlots to read the slots of the range by query, from its page token.
+func pageSlots(query func(start, end uint32) ([]*Slot, error), rang *SlotRange) (*slotPager, error) {
+	p := &slotPager{
+		query:  query,
+		start:  rang.Start,
+		end:    rang.End,
+		window: defaultSlotWindow,
+	}
+	if p.end == 0 {
+		p.end = math.MaxUint32
+	}
+	if len(rang.PageToken) != 0 {
+		var err error
+		if p.start, p.toSkip, p.window, err = decodePageToken(rang.PageToken); err != nil {
+			return nil, err
+		}
+		if p.start < rang.Start {
+			return nil, ErrPageToken
+		}
+	}
+	p.pos = p.start
+	return p, nil
+}
+
+// next to get the next page of at most limit slots, all the slots left with
+// a limit of 0. The token resumes after the page, nil on the last one.
+func (p *slotPager) next(limit uint32) ([]*Slot, []byte, error) {
+	if limit == 0 {
+		p.window = 0
+	}
+	// one slot more than limit tells whether a page follows
+	for !p.done && (limit == 0 || uint32(len(p.buf)) <= limit) {
+		if err := p.load(limit + 1 - uint32(len(p.buf))); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if limit == 0 || uint32(len(p.buf)) <= limit {
+		page := p.buf
+		p.buf = nil
+		return page, nil, nil
+	}
+
+	page, next := p.buf[:limit], p.buf[limit]
+	nextSkip := uint32(0)
+	for _, slot := range page {
+		if slot.Start == next.Start {
+			nextSkip++
+		}
+	}
+	if next.Start == p.start {
+		nextSkip += p.skip
+	}
+	p.start, p.skip, p.buf = next.Start, nextSkip, p.buf[limit:]
+	return page, encodePageToken(p.start, p.skip, p.window), nil
+}
+
+// load to query the next window, want slots are still needed for the page.
+func (p *slotPager) load(want uint32) error {
+	wEnd := p.end
+	if p.window != 0 && p.pos < p.end && p.end-p.pos > p.window {
+		wEnd = p.pos + p.window
+	}
+	slots, err := p.query(p.pos, wEnd)
+	if err != nil {
+		return err
+	}
+
+	if wEnd == p.end {
+		p.done = true
+	} else {
+		// the end of a window is the start of the next one
+		slots = slots[:sort.Search(len(slots), func(i int) bool { return slots[i].Start >= wEnd })]
+		p.pos = wEnd
+
+		switch n := uint64(len(slots)); {
+		case n < uint64(want):
+			if p.window *= 2; p.window > maxSlotWindow {
+				p.window = maxSlotWindow
+			}
+		case n > 2*uint64(want):
+			if p.window /= 2; p.window < minSlotWindow {
+				p.window = minSlotWindow
+			}
+		}
+	}
+
+	if p.toSkip != 0 {
+		// the slots already returned at start, all in the first window
+		slots, p.skip = skipSlots(slots, p.start, p.toSkip)
+		p.toSkip = 0
+	}
+	p.buf = append(p.buf, slots...)
+	return nil
+}
+
+// querySlots to get one page of slots in the range, of the targets in the
+// namespace prefix.
+func querySlots(rang *SlotRange, prefix string) (*Slots, error) {
+	p, err := newSlotPager(rang, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var all Slots
+	if all.Slots, all.NextPageToken, err = p.next(rang.Limit); err != nil {
+		return nil, err
+	}
+	return &all, nil
+}
+
+// chunkSlots to emit the slots of a pager as pages of at most limit slots.
+// Each page has the token to resume after it, the last one has none.
+func chunkSlots(p *slotPager, limit uint32, emit func(*Slots)) error {
+	for {
+		page, token, err := p.next(limit)
+		if err != nil {
+			return err
+		}
+		emit(&Slots{Slots: page, NextPageToken: token})
+		if token == nil {
+			return nil
+		}
+	}
+}
+
+// slotQuery to get how to query the slots of the range between two times, of
+// the targets in the namespace prefix.
+func slotQuery(rang *SlotRange, prefix string) (func(start, end uint32) ([]*Slot, error), error) {
+	m, err := filterMatcher(rang.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if m == nil {
+		return func(start, end uint32) ([]*Slot, error) {
+			startsResult, slotsResult, err := db.GetSlots(prefix+rang.Target, start, end)
+			if err != nil {
+				return nil, err
+			}
+			return flattenSlots(startsResult, slotsResult), nil
+		}, nil
+	}
+
+	targets := []string{rang.Target}
+	if len(rang.Target) == 0 {
+		targets = scopeTargets(prefix, db.GetTargets())
+	}
+	targets = m.filter(targets)
+	return func(start, end uint32) ([]*Slot, error) {
+		return matchedSlots(targets, prefix, start, end)
+	}, nil
+}
+
+func flattenSlots(startsResult, slotsResult [][]uint32) []*Slot {
+	var slots []*Slot
+	for i := 0; i < len(startsResult); i++ {
+		oneStarts, oneSlots := startsResult[i], slotsResult[i]
+		for j := 0; j < len(oneStarts); j++ {
+			slots = append(slots, &Slot{
+				Start: oneStarts[j],
+				Slot:  oneSlots[j],
+			})
+		}
+	}
+	sort.SliceStable(slots, func(i, j int) bool { return slots[i].Start < slots[j].Start })
+	return slots
+}
+
+// matchedSlots to get the slots of the targets matched in the namespace
+// prefix. Slots are sorted by start then target.
+func matchedSlots(targets []string, prefix string, start, end uint32) ([]*Slot, error) {
+	var slots []*Slot
+	for _, t := range targets {
+		startsResult, slotsResult, err := db.GetSlots(prefix+t, start, end)
+		if err != nil {
+			return nil, err
+		}
+		for _, slot := range flattenSlots(startsResult, slotsResult) {
+			slot.Target = t
+			slots = append(slots, slot)
+		}
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool {
+		if slots[i].Start != slots[j].Start {
+			return slots[i].Start < slots[j].Start
+		}
+		return slots[i].Target < slots[j].Target
+	})
+	return slots, nil
+}
+
+// skipSlots to drop the first skip slots at start, already returned.
+func skipSlots(slots []*Slot, start, skip uint32) ([]*Slot, uint32) {
+	skipped := uint32(0)
+	for skipped < skip && len(slots) > 0 && slots[0].Start == start {
+		slots = slots[1:]
+		skipped++
+	}
+	return slots, skipped
+}
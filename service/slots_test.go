@@ -0,0 +1,142 @@
+package service
+
+import (
+	"math"
+	"testing"
+)
+
+// yearSlots to get slots sorted by start over a year, 3 a day, with 5 slots
+// at the same start in the middle.
+func yearSlots() []*Slot {
+	var all []*Slot
+	for day := uint32(0); day < 365; day++ {
+		for i := uint32(0); i < 3; i++ {
+			all = append(all, &Slot{Start: 1500000000 + day*24*3600 + i*3600, Slot: uint32(len(all))})
+		}
+		if day == 100 {
+			for i := 0; i < 4; i++ {
+				all = append(all, &Slot{Start: all[len(all)-1].Start, Slot: uint32(len(all))})
+			}
+		}
+	}
+	return all
+}
+
+// fakeQuery to query slots like db, adding the slots queried to loaded.
+func fakeQuery(all []*Slot, loaded *int) func(start, end uint32) ([]*Slot, error) {
+	return func(start, end uint32) ([]*Slot, error) {
+		var slots []*Slot
+		for _, slot := range all {
+			if slot.Start >= start && slot.Start <= end {
+				slots = append(slots, slot)
+			}
+		}
+		*loaded += len(slots)
+		return slots, nil
+	}
+}
+
+func checkSlots(t *testing.T, all, got []*Slot) {
+	t.Helper()
+	if len(got) != len(all) {
+		t.Fatalf("expect %d slots, got %d", len(all), len(got))
+	}
+	for i := range all {
+		if got[i] != all[i] {
+			t.Fatalf("slot %d: expect %v, got %v", i, all[i], got[i])
+		}
+	}
+}
+
+func TestPageSlots(t *testing.T) {
+	all := yearSlots()
+
+	// follow the pages like the list command, a query a page
+	var got []*Slot
+	total := 0
+	rang := &SlotRange{Start: all[0].Start}
+	for pages := 0; ; pages++ {
+		if pages > len(all) {
+			t.Fatal("too many pages")
+		}
+		loaded := 0
+		p, err := pageSlots(fakeQuery(all, &loaded), rang)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, token, err := p.next(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) > 2 {
+			t.Fatalf("page of %d slots", len(page))
+		}
+		if loaded > 20 {
+			t.Fatalf("page %d loaded %d slots", pages, loaded)
+		}
+		got, total = append(got, page...), total+loaded
+		if token == nil {
+			break
+		}
+		rang.PageToken = token
+	}
+	checkSlots(t, all, got)
+	if total > 5*len(all) {
+		t.Errorf("loaded %d slots for %d", total, len(all))
+	}
+
+	loaded := 0
+	p, _ := pageSlots(fakeQuery(all, &loaded), &SlotRange{})
+	if page, token, _ := p.next(0); len(page) != len(all) || token != nil || p.end != math.MaxUint32 {
+		t.Error("limit 0 should return all slots, up to the last time without end")
+	}
+	if _, _, _, err := decodePageToken([]byte{1}); err != ErrPageToken {
+		t.Errorf("expect %v, got %v", ErrPageToken, err)
+	}
+	if _, _, _, err := decodePageToken(encodePageToken(1, 0, 0)); err != ErrPageToken {
+		t.Errorf("a window of 0 should be wrong, got %v", err)
+	}
+}
+
+func TestChunkSlots(t *testing.T) {
+	all := yearSlots()
+
+	loaded := 0
+	p, err := pageSlots(fakeQuery(all, &loaded), &SlotRange{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chunks []*Slots
+	if err := chunkSlots(p, 100, func(chunk *Slots) { chunks = append(chunks, chunk) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != (len(all)+99)/100 {
+		t.Fatalf("expect %d chunks, got %d", (len(all)+99)/100, len(chunks))
+	}
+	// only the slots at the end of a window are queried twice
+	if loaded >= 2*len(all) {
+		t.Errorf("loaded %d slots for %d", loaded, len(all))
+	}
+
+	var got []*Slot
+	for i, chunk := range chunks {
+		got = append(got, chunk.Slots...)
+		if last := i == len(chunks)-1; last != (chunk.NextPageToken == nil) {
+			t.Errorf("chunk %d: wrong token %v", i, chunk.NextPageToken)
+		}
+		if chunk.NextPageToken == nil {
+			continue
+		}
+
+		// the token of a chunk resumes a paged query right after it
+		n := 0
+		p, err := pageSlots(fakeQuery(all, &n), &SlotRange{PageToken: chunk.NextPageToken})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page, _, _ := p.next(1); page[0] != chunks[i+1].Slots[0] {
+			t.Errorf("chunk %d: token resumes at %v", i, page[0])
+		}
+	}
+	checkSlots(t, all, got)
+}
@@ -1,26 +1,47 @@
 package service
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
+	"os"
 	"reflect"
 	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/tracerun/tracerun/lg"
 	"go.uber.org/zap"
 )
 
-const (
-	readTimeout = 30
-)
+// DefaultReadTimeout for a connection without frames
+const DefaultReadTimeout = 30 * time.Second
+
+// readTimeout for a connection without frames
+var readTimeout = DefaultReadTimeout
 
 // TCPServer to define a TCP server
 type TCPServer struct {
+	// Hosts to listen on, a host or "host:port" with its own port. All
+	// interfaces if empty.
+	Hosts []string
+	// TLS of the listeners, plaintext if nil
+	TLS *tls.Config
+
 	port   uint16
 	router map[uint8]RouteFunc
-	ln     net.Listener
+	// socket path and its mode for a Unix socket server
+	socket string
+	mode   os.FileMode
+
+	mu       sync.Mutex
+	lns      []net.Listener
+	stopping bool
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
 }
 
 // NewTCPServer to create a TCP server instance
@@ -28,69 +49,237 @@ func NewTCPServer(port uint16, router map[uint8]RouteFunc) *TCPServer {
 	return &TCPServer{
 		port:   port,
 		router: router,
+		conns:  make(map[net.Conn]struct{}),
 	}
 }
 
-// Start the server
+// Start the server, it returns nil after the server is stopped.
 func (s *TCPServer) Start() error {
-	if s.ln != nil {
+	if err := s.listen(); err != nil {
+		return err
+	}
+	return s.serve()
+}
+
+// listenAddr to get the address of a host, "host:port" keeps its port.
+func listenAddr(host string, port uint16) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), fmt.Sprint(port))
+}
+
+// listen on all the addresses of the server, or on none if one fails.
+func (s *TCPServer) listen() error {
+	var lns []net.Listener
+	var addrs []string
+	if len(s.socket) != 0 {
+		ln, err := s.listenUnix()
+		if err != nil {
+			return err
+		}
+		lns, addrs = append(lns, ln), append(addrs, s.socket)
+	} else {
+		hosts := s.Hosts
+		if len(hosts) == 0 {
+			hosts = []string{""}
+		}
+		for _, host := range hosts {
+			addr := listenAddr(host, s.port)
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				for _, ln := range lns {
+					ln.Close()
+				}
+				return err
+			}
+			if s.TLS != nil {
+				ln = tls.NewListener(ln, s.TLS)
+			}
+			lns, addrs = append(lns, ln), append(addrs, addr)
+		}
+	}
+
+	s.mu.Lock()
+	if s.lns != nil || s.stopping {
+		s.mu.Unlock()
+		for _, ln := range lns {
+			ln.Close()
+		}
 		return fmt.Errorf("already started")
 	}
+	s.lns = lns
+	s.mu.Unlock()
+	lg.L.Info("started to listen socket connections", zap.Strings("addrs", addrs))
+	return nil
+}
 
-	port := fmt.Sprintf(":%d", s.port)
-	// net.ListenUDP("udp", laddr*net.UDPAddr)
-	ln, err := net.Listen("tcp", port)
-	if err != nil {
-		return err
+// serve the connections accepted, it returns nil after the server is
+// stopped.
+func (s *TCPServer) serve() error {
+	s.mu.Lock()
+	lns := s.lns
+	s.mu.Unlock()
+
+	errs := make(chan error, len(lns))
+	for _, ln := range lns {
+		go func(ln net.Listener) {
+			errs <- s.accept(ln)
+		}(ln)
 	}
 
-	s.ln = ln
-	lg.L.Info("started to listen socket connections", zap.Uint16("port", s.port))
+	var err error
+	for range lns {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
 
+// accept the connections of a listener until the server is stopped.
+func (s *TCPServer) accept(ln net.Listener) error {
 	for {
 		conn, err := ln.Accept()
-
 		if err != nil {
+			if s.isStopping() {
+				return nil
+			}
 			lg.L.Error("error accept connection", zap.Error(err))
+			continue
 		}
 		lg.L.Debug("new connection come")
 
+		if !s.track(conn) {
+			conn.Close()
+			continue
+		}
 		go s.handleConn(conn)
 	}
 }
 
-// Stop the server
+// Stop the server from accepting new connections.
 func (s *TCPServer) Stop() error {
-	if s.ln != nil {
-		return s.ln.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopping {
+		return nil
 	}
-	return nil
+	s.stopping = true
+	var err error
+	for _, ln := range s.lns {
+		if e := ln.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Shutdown the server. It stops accepting new connections, lets every open
+// connection finish the request in hand and waits for them at most timeout.
+// Connections still open after timeout are closed.
+func (s *TCPServer) Shutdown(timeout time.Duration) error {
+	err := s.Stop()
+
+	// wake up the connections waiting for the next request
+	s.mu.Lock()
+	for c := range s.conns {
+		c.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		s.mu.Lock()
+		lg.L.Warn("closing connections after timeout", zap.Int("count", len(s.conns)))
+		for c := range s.conns {
+			c.Close()
+		}
+		s.mu.Unlock()
+		<-done
+	}
+	return err
+}
+
+func (s *TCPServer) isStopping() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopping
+}
+
+// track a new connection, false if the server is stopping.
+func (s *TCPServer) track(c net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopping {
+		return false
+	}
+	s.conns[c] = struct{}{}
+	s.wg.Add(1)
+	atomic.AddInt64(&openConns, 1)
+	return true
+}
+
+func (s *TCPServer) untrack(c net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, c)
+	s.mu.Unlock()
+	atomic.AddInt64(&openConns, -1)
+	s.wg.Done()
 }
 
 func (s *TCPServer) handleConn(c net.Conn) {
+	defer s.untrack(c)
 	defer func() {
 		if r := recover(); r != nil {
 			lg.L.Warn("recovered", zap.Any("error", r), zap.Stack("info"))
 		}
 	}()
 
+	sess := newSession(c)
+	if uc, ok := c.(*net.UnixConn); ok {
+		sess.remote = "unix:" + s.socket
+		if peerTrusted(uc) {
+			sess.perm, sess.local = PermAdmin, true
+		}
+	} else {
+		sess.remote = c.RemoteAddr().String()
+	}
 	for {
 		// read header
-		c.SetReadDeadline(time.Now().Add(readTimeout * time.Second))
-		data, route, err := ReadOne(c)
+		settingsMu.RLock()
+		timeout := readTimeout
+		settingsMu.RUnlock()
+		c.SetReadDeadline(time.Now().Add(timeout))
+		if s.isStopping() {
+			break
+		}
+		data, route, err := sess.readFrame(c)
 		if err != nil {
 			recordConnError(err)
 			break
 		}
 		lg.L.Debug("data", zap.Uint8("route", route), zap.Binary("data", data))
+		atomic.AddUint64(&routeCounts[route], 1)
 
 		// get routed function
 		fn, ok := s.router[route]
 		if !ok {
 			lg.L.Warn("not found")
+		} else if routePerm(route) > sess.perm {
+			lg.L.Debug("route unauthorized", zap.Uint8("route", route))
+			WriteErrorMessage(ErrUnauthorized, sess)
 		} else {
 			lg.L.Debug(runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name())
-			fn(data, c)
+			fn(data, sess)
 		}
 	}
 
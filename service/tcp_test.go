@@ -1,7 +1,11 @@
 package service
 
 import (
+	"net"
 	"testing"
+	"time"
+
+	"github.com/tracerun/tracerun/lg"
 )
 
 func TestSocket(t *testing.T) {
@@ -12,3 +16,105 @@ func TestSocket(t *testing.T) {
 
 	// s.Start()
 }
+
+func TestShutdown(t *testing.T) {
+	lg.InitLogger(false, true, "")
+
+	port := uint16(8871)
+	s := NewTCPServer(port, getRouter())
+	started := make(chan error, 1)
+	go func() { started <- s.Start() }()
+
+	var conn net.Conn
+	var err error
+	for i := 0; i < 50; i++ {
+		if conn, err = net.Dial("tcp", "127.0.0.1:8871"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// ping to make sure the connection is handled
+	if _, err := conn.Write(GenerateHeaderBuf(0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	begin := time.Now()
+	if err := s.Shutdown(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(begin) > 500*time.Millisecond {
+		t.Error("idle connection should not delay shutdown")
+	}
+	if err := <-started; err != nil {
+		t.Error(err)
+	}
+
+	// the connection is closed by the server
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("connection should be closed")
+	}
+}
+
+func TestListenAddresses(t *testing.T) {
+	lg.InitLogger(false, true, "")
+
+	tests := []struct {
+		host   string
+		expect string
+	}{
+		{"", ":8875"},
+		{"127.0.0.1", "127.0.0.1:8875"},
+		{"::1", "[::1]:8875"},
+		{"[::1]", "[::1]:8875"},
+		{"[::1]:9000", "[::1]:9000"},
+		{"localhost:9000", "localhost:9000"},
+	}
+	for _, test := range tests {
+		if addr := listenAddr(test.host, 8875); addr != test.expect {
+			t.Errorf("%q: expect %s, got %s", test.host, test.expect, addr)
+		}
+	}
+
+	hosts := []string{"127.0.0.1", "127.0.0.1:8876"}
+	if ln, err := net.Listen("tcp", "[::1]:0"); err == nil {
+		ln.Close()
+		hosts = append(hosts, "::1")
+	}
+	s := NewTCPServer(8875, getRouter())
+	s.Hosts = hosts
+	if err := s.listen(); err != nil {
+		t.Fatal(err)
+	}
+	go s.serve()
+	defer s.Shutdown(time.Second)
+
+	for _, addr := range []string{"127.0.0.1:8875", "127.0.0.1:8876", "[::1]:8875"} {
+		if addr == "[::1]:8875" && len(hosts) == 2 {
+			continue
+		}
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Errorf("%s: %v", addr, err)
+			continue
+		}
+		conn.Close()
+	}
+
+	// one address taken, no listener is kept
+	other := NewTCPServer(8877, getRouter())
+	other.Hosts = []string{"127.0.0.1", "127.0.0.1:8875"}
+	if err := other.listen(); err == nil {
+		t.Fatal("a taken address should fail")
+	}
+	if conn, err := net.Dial("tcp", "127.0.0.1:8877"); err == nil {
+		conn.Close()
+		t.Error("the listener of a failed listen should be closed")
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	// ErrTLSConfig a certificate given without its key, or the other way
+	// round
+	ErrTLSConfig = errors.New("tls needs both a certificate and a key")
+	// ErrUDPWithTLS datagrams asked with TLS, they would carry actions and
+	// tokens in clear
+	ErrUDPWithTLS = errors.New("udp can't be used with tls, datagrams are not encrypted")
+)
+
+// TLSConfig to get the TLS config of a listener, nil if cert and key are
+// empty. With clientCA, clients must show a certificate signed by it.
+func TLSConfig(cert, key, clientCA string) (*tls.Config, error) {
+	if len(cert) == 0 && len(key) == 0 {
+		if len(clientCA) != 0 {
+			return nil, ErrTLSConfig
+		}
+		return nil, nil
+	}
+	if len(cert) == 0 || len(key) == 0 {
+		return nil, ErrTLSConfig
+	}
+
+	pair, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if len(clientCA) != 0 {
+		if cfg.ClientCAs, err = LoadCertPool(clientCA); err != nil {
+			return nil, err
+		}
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// LoadCertPool to read the PEM certificates of a file.
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificate in %s", file)
+	}
+	return pool, nil
+}
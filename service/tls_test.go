@@ -0,0 +1,169 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tracerun/tracerun/lg"
+)
+
+// testCert to write a certificate and its key signed by parent, or self
+// signed if parent is nil. The files are dir/name.pem and dir/name.key.
+func testCert(t *testing.T, dir, name string, ca bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:         ca,
+
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(filepath.Join(dir, name+".pem"), certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	testCert(t, dir, "server", false, nil, nil)
+	file := func(name string) string { return filepath.Join(dir, name) }
+
+	if cfg, err := TLSConfig("", "", ""); cfg != nil || err != nil {
+		t.Errorf("expect plaintext, got %v %v", cfg, err)
+	}
+	for _, files := range [][3]string{
+		{file("server.pem"), "", ""},
+		{"", file("server.key"), ""},
+		{"", "", file("server.pem")},
+	} {
+		if _, err := TLSConfig(files[0], files[1], files[2]); err != ErrTLSConfig {
+			t.Errorf("%v: expect ErrTLSConfig, got %v", files, err)
+		}
+	}
+	if _, err := TLSConfig(file("server.pem"), file("server.key"), file("server.key")); err == nil {
+		t.Error("a client CA without certificate should fail")
+	}
+}
+
+func TestUDPWithTLS(t *testing.T) {
+	if err := Start(Config{UDP: true, TLSCert: "cert.pem", TLSKey: "key.pem"}); err != ErrUDPWithTLS {
+		t.Errorf("expect %v, got %v", ErrUDPWithTLS, err)
+	}
+}
+
+func TestTLSServer(t *testing.T) {
+	lg.InitLogger(false, true, "")
+	dir := t.TempDir()
+	file := func(name string) string { return filepath.Join(dir, name) }
+
+	ca, caKey := testCert(t, dir, "ca", true, nil, nil)
+	testCert(t, dir, "server", false, ca, caKey)
+	testCert(t, dir, "client", false, ca, caKey)
+	testCert(t, dir, "stranger", false, nil, nil)
+
+	cfg, err := TLSConfig(file("server.pem"), file("server.key"), file("ca.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := map[uint8]RouteFunc{
+		1: func(b []byte, w io.Writer) { w.Write(GenerateHeaderBuf(0, 1)) },
+	}
+	s := NewTCPServer(8880, router)
+	s.Hosts = []string{"127.0.0.1"}
+	s.TLS = cfg
+	if err := s.listen(); err != nil {
+		t.Fatal(err)
+	}
+	go s.serve()
+	defer s.Shutdown(time.Second)
+
+	pool, err := LoadCertPool(file("ca.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ping := func(name string) error {
+		clientCfg := &tls.Config{RootCAs: pool}
+		if len(name) != 0 {
+			pair, err := tls.LoadX509KeyPair(file(name+".pem"), file(name+".key"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			clientCfg.Certificates = []tls.Certificate{pair}
+		}
+		conn, err := tls.Dial("tcp", "127.0.0.1:8880", clientCfg)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(time.Second))
+		if _, err := conn.Write(GenerateHeaderBuf(0, 1)); err != nil {
+			return err
+		}
+		_, _, err = ReadOne(conn)
+		return err
+	}
+
+	if err := ping("client"); err != nil {
+		t.Errorf("a client signed by the CA should ping, %v", err)
+	}
+	if err := ping(""); err == nil {
+		t.Error("a client without certificate should fail")
+	}
+	if err := ping("stranger"); err == nil {
+		t.Error("a client not signed by the CA should fail")
+	}
+
+	// plaintext gets no reply
+	conn, err := net.Dial("tcp", "127.0.0.1:8880")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+	conn.Write(GenerateHeaderBuf(0, 1))
+	if _, route, err := ReadOne(conn); err == nil && route == 1 {
+		t.Error("a plaintext client should fail")
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// DefaultSeparator between the levels of a target
+const DefaultSeparator = "/"
+
+var (
+	// ErrSeparator an empty separator
+	ErrSeparator = errors.New("empty target separator")
+
+	// separator between the levels of a target, like "project/repo/file.go"
+	separator = DefaultSeparator
+)
+
+// splitTarget to get the levels of a target, empty levels are skipped.
+func splitTarget(target string) []string {
+	var levels []string
+	for _, level := range strings.Split(target, separator) {
+		if len(level) != 0 {
+			levels = append(levels, level)
+		}
+	}
+	return levels
+}
+
+// rollUp to get the prefix of a target with at most depth levels, 0 depth to
+// keep the target.
+func rollUp(target string, depth uint32) string {
+	if depth == 0 {
+		return target
+	}
+	levels := splitTarget(target)
+	if uint32(len(levels)) <= depth {
+		return strings.Join(levels, separator)
+	}
+	return strings.Join(levels[:depth], separator)
+}
+
+// underPrefix to check whether a target is the prefix or below it.
+func underPrefix(target, prefix string) bool {
+	levels, prefixLevels := splitTarget(target), splitTarget(prefix)
+	if len(levels) < len(prefixLevels) {
+		return false
+	}
+	for i := range prefixLevels {
+		if levels[i] != prefixLevels[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// addToTree to add the seconds of a target to the node and its descendants
+// on the target path, at most depth levels below the node, 0 for no limit.
+func addToTree(node *TargetNode, levels []string, seconds uint64, depth uint32) {
+	node.Seconds += seconds
+	if len(levels) == 0 || depth == 1 {
+		return
+	}
+
+	var child *TargetNode
+	for _, c := range node.Children {
+		if c.Name == levels[0] {
+			child = c
+			break
+		}
+	}
+	if child == nil {
+		path := levels[0]
+		if len(node.Path) != 0 {
+			path = node.Path + separator + levels[0]
+		}
+		child = &TargetNode{Name: levels[0], Path: path}
+		node.Children = append(node.Children, child)
+	}
+
+	if depth > 0 {
+		depth--
+	}
+	addToTree(child, levels[1:], seconds, depth)
+}
+
+func sortTree(node *TargetNode) {
+	sort.Slice(node.Children, func(i, j int) bool { return node.Children[i].Name < node.Children[j].Name })
+	for _, c := range node.Children {
+		sortTree(c)
+	}
+}
+
+// buildTree to get the targets under a prefix as a tree, each node has the
+// total seconds of the targets below it in the range. Targets are the ones in
+// the namespace scope.
+func buildTree(req *TreeRequest, scope string) (*TargetNode, error) {
+	prefixLevels := splitTarget(req.Prefix)
+	root := &TargetNode{
+		Path: strings.Join(prefixLevels, separator),
+	}
+	if len(prefixLevels) != 0 {
+		root.Name = prefixLevels[len(prefixLevels)-1]
+	}
+
+	// depth counts the levels below root, the root itself is one more
+	depth := req.Depth
+	if depth > 0 {
+		depth++
+	}
+
+	for _, target := range scopeTargets(scope, db.GetTargets()) {
+		if !underPrefix(target, req.Prefix) {
+			continue
+		}
+
+		startsResult, slotsResult, err := db.GetSlots(scope+target, req.Start, req.End)
+		if err != nil {
+			return nil, err
+		}
+		seconds := totalSeconds(flattenSlots(startsResult, slotsResult), req.Start, req.End)
+		addToTree(root, splitTarget(target)[len(prefixLevels):], seconds, depth)
+	}
+
+	sortTree(root)
+	return root, nil
+}
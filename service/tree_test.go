@@ -0,0 +1,57 @@
+package service
+
+import "testing"
+
+func TestRollUp(t *testing.T) {
+	tests := []struct {
+		target string
+		depth  uint32
+		expect string
+	}{
+		{"project/repo/file.go", 0, "project/repo/file.go"},
+		{"project/repo/file.go", 1, "project"},
+		{"project/repo/file.go", 2, "project/repo"},
+		{"/project/repo/", 5, "project/repo"},
+	}
+	for _, test := range tests {
+		if got := rollUp(test.target, test.depth); got != test.expect {
+			t.Errorf("%s at %d: expect %s, got %s", test.target, test.depth, test.expect, got)
+		}
+	}
+
+	if !underPrefix("project/repo/file.go", "project/repo") || !underPrefix("project", "") {
+		t.Error("target should be under the prefix")
+	}
+	if underPrefix("project/repository", "project/repo") || underPrefix("project", "project/repo") {
+		t.Error("target should not be under the prefix")
+	}
+}
+
+func TestAddToTree(t *testing.T) {
+	root := &TargetNode{}
+	addToTree(root, splitTarget("p/a/x.go"), 10, 0)
+	addToTree(root, splitTarget("p/a/y.go"), 20, 0)
+	addToTree(root, splitTarget("p/b/z.go"), 30, 0)
+	addToTree(root, splitTarget("q"), 40, 0)
+	sortTree(root)
+
+	if root.Seconds != 100 || len(root.Children) != 2 {
+		t.Fatalf("wrong root %v", root)
+	}
+	p := root.Children[0]
+	if p.Name != "p" || p.Seconds != 60 || len(p.Children) != 2 {
+		t.Fatalf("wrong node %v", p)
+	}
+	a := p.Children[0]
+	if a.Path != "p/a" || a.Seconds != 30 || len(a.Children) != 2 || a.Children[1].Path != "p/a/y.go" {
+		t.Errorf("wrong node %v", a)
+	}
+
+	// the root and one level below
+	shallow := &TargetNode{}
+	addToTree(shallow, splitTarget("p/a/x.go"), 10, 2)
+	addToTree(shallow, splitTarget("p/b/z.go"), 30, 2)
+	if shallow.Seconds != 40 || len(shallow.Children) != 1 || len(shallow.Children[0].Children) != 0 {
+		t.Errorf("wrong shallow tree %v", shallow)
+	}
+}
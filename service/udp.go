@@ -1,16 +1,48 @@
 package service
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/tracerun/tracerun/lg"
 	"go.uber.org/zap"
 )
 
-// UDPServer to define a UDP server
+const (
+	// maxDatagramBytes the largest UDP payload
+	maxDatagramBytes = 65507
+	// minReadDelay and maxReadDelay bound the delay after a temporary read
+	// error
+	minReadDelay = 5 * time.Millisecond
+	maxReadDelay = time.Second
+)
+
+// ErrDatagram a datagram not made of whole frames
+var ErrDatagram = errors.New("datagram with a truncated frame")
+
+// UDPServer to receive actions by datagram, without reply. A datagram has
+// one or more Version1 frames, each of them for an action route.
 type UDPServer struct {
+	// Hosts to listen on, like the ones of TCPServer, a port given is
+	// ignored. All interfaces if empty.
+	Hosts []string
+
 	port   uint16
 	router map[uint8]RouteFunc
+
+	mu       sync.Mutex
+	conns    []*net.UDPConn
+	stopping bool
+	// wg counts the goroutines reading the conns
+	wg sync.WaitGroup
 }
 
 // NewUDPServer to create a server instance
@@ -21,45 +53,184 @@ func NewUDPServer(port uint16, router map[uint8]RouteFunc) *UDPServer {
 	}
 }
 
-// Start the UDP server
-func (s *UDPServer) Start() {
-	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: int(s.port)})
-	if err != nil {
-		lg.L.Error("error accept connection", zap.Error(err))
-		panic(err)
+// udpRouter the routes accepted by datagram, the ones which don't reply
+// unless the action is rejected.
+func udpRouter() map[uint8]RouteFunc {
+	return map[uint8]RouteFunc{
+		10: action,
+		12: timedAction,
+	}
+}
+
+// Start the UDP server, it returns nil after the server is stopped.
+func (s *UDPServer) Start() error {
+	if err := s.listen(); err != nil {
+		return err
+	}
+	return s.serve()
+}
+
+// listen on all the hosts of the server, or on none if one fails.
+func (s *UDPServer) listen() error {
+	hosts := s.Hosts
+	if len(hosts) == 0 {
+		hosts = []string{""}
+	}
+
+	var conns []*net.UDPConn
+	var addrs []string
+	for _, host := range hosts {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(strings.Trim(host, "[]"), fmt.Sprint(s.port)))
+		if err == nil {
+			var conn *net.UDPConn
+			if conn, err = net.ListenUDP("udp", addr); err == nil {
+				conns, addrs = append(conns, conn), append(addrs, addr.String())
+				continue
+			}
+		}
+		for _, conn := range conns {
+			conn.Close()
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	if s.conns != nil || s.stopping {
+		s.mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+		return fmt.Errorf("already started")
+	}
+	s.conns = conns
+	s.wg.Add(len(conns))
+	s.mu.Unlock()
+	lg.L.Info("started to listen udp connections", zap.Strings("addrs", addrs))
+	return nil
+}
+
+// serve the datagrams of the hosts listened, it returns nil after the server
+// is stopped.
+func (s *UDPServer) serve() error {
+	s.mu.Lock()
+	conns := s.conns
+	s.mu.Unlock()
+
+	for _, conn := range conns {
+		go func(conn *net.UDPConn) {
+			defer s.wg.Done()
+			s.handleUDPConn(conn)
+		}(conn)
+	}
+	s.wg.Wait()
+	return nil
+}
+
+// Stop the server, it returns after the datagrams in hand are handled.
+func (s *UDPServer) Stop() error {
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		s.wg.Wait()
+		return nil
+	}
+	s.stopping = true
+	var err error
+	for _, conn := range s.conns {
+		if e := conn.Close(); e != nil && err == nil {
+			err = e
+		}
 	}
-	lg.L.Info("started to listen udp connections", zap.Uint16("port", s.port))
+	s.mu.Unlock()
 
-	s.handleUDPConn(conn)
+	s.wg.Wait()
+	return err
 }
 
+func (s *UDPServer) isStopping() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopping
+}
+
+// handleUDPConn to read the datagrams of a conn until the server stops. A
+// temporary read error is retried after a delay, growing up to
+// maxReadDelay, other errors stop reading the conn.
 func (s *UDPServer) handleUDPConn(c *net.UDPConn) {
+	buf := make([]byte, maxDatagramBytes)
+	var delay time.Duration
+	for {
+		n, addr, err := c.ReadFromUDP(buf)
+		if err != nil {
+			if s.isStopping() {
+				break
+			}
+			if ne, ok := err.(net.Error); !ok || !ne.Temporary() {
+				lg.L.Error("error to read datagram, udp conn stopped", zap.Stringer("addr", c.LocalAddr()), zap.Error(err))
+				break
+			}
+			if delay *= 2; delay == 0 {
+				delay = minReadDelay
+			} else if delay > maxReadDelay {
+				delay = maxReadDelay
+			}
+			lg.L.Warn("error to read datagram, retry", zap.Duration("delay", delay), zap.Error(err))
+			time.Sleep(delay)
+			continue
+		}
+		delay = 0
+
+		if err := s.handleDatagram(buf[:n]); err != nil {
+			lg.L.Debug("datagram dropped", zap.Stringer("from", addr), zap.Error(err))
+		}
+	}
+	lg.L.Debug("udp connection closed")
+}
+
+// handleDatagram to route the frames of a datagram, the frames after a
+// truncated one are dropped. With authentication on, the datagram starts
+// with an AuthRequest frame.
+func (s *UDPServer) handleDatagram(datagram []byte) error {
 	defer func() {
 		if r := recover(); r != nil {
 			lg.L.Warn("recovered", zap.Any("error", r), zap.Stack("info"))
 		}
 	}()
 
-	for {
-		// read one request
-		data, route, err := ReadOne(c)
+	sess := &session{Writer: io.Discard, version: Version1, perm: defaultPerm()}
+	r := bytes.NewReader(datagram)
+	for r.Len() > 0 {
+		data, route, err := ReadOne(r)
 		if err != nil {
-			lg.L.Error("error to read data", zap.Error(err))
-			break
+			return ErrDatagram
+		}
+		atomic.AddUint64(&routeCounts[route], 1)
+
+		if route == authRoute {
+			var req AuthRequest
+			if err := proto.Unmarshal(data, &req); err != nil {
+				return err
+			}
+			acc, err := authenticate(req.Token)
+			if err != nil {
+				return err
+			}
+			sess.perm, sess.user, sess.host = acc.perm, acc.user, req.Host
+			continue
+		}
+		if routePerm(route) > sess.perm {
+			return ErrUnauthorized
 		}
-		lg.L.Debug("data", zap.Uint8("route", route), zap.Binary("data", data))
 
-		// get routed function
 		fn, ok := s.router[route]
 		if !ok {
-			lg.L.Warn("not found")
-		} else {
-			fn(data, c)
+			lg.L.Debug("route rejected by datagram", zap.Uint8("route", route))
+			continue
 		}
+		fn(data, sess)
 	}
-
-	if err := c.Close(); err != nil {
-		lg.L.Error("error close", zap.Error(err))
-	}
-	lg.L.Debug("connection closed")
+	return nil
 }
@@ -1,10 +1,115 @@
 package service
 
-// func TestSocket(t *testing.T) {
-// 	lg.InitLogger(true, false, "")
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
 
-// 	port := uint16(8870)
-// 	s := NewUDPServer(port, nil)
+	"github.com/tracerun/tracerun/lg"
+)
 
-// 	s.Start()
-// }
+func frame(route uint8, data string) []byte {
+	return append(GenerateHeaderBuf(uint16(len(data)), route), data...)
+}
+
+func TestUDPServer(t *testing.T) {
+	lg.InitLogger(false, true, "")
+
+	got := make(chan string, 10)
+	router := map[uint8]RouteFunc{
+		10: func(b []byte, w io.Writer) { got <- string(b) },
+	}
+	s := NewUDPServer(8878, router)
+	s.Hosts = []string{"127.0.0.1"}
+
+	// a query route is skipped, the frames after a truncated one are dropped
+	var datagram []byte
+	datagram = append(datagram, frame(10, "a.go")...)
+	datagram = append(datagram, frame(20, "")...)
+	datagram = append(datagram, frame(10, "b.go")...)
+	if err := s.handleDatagram(datagram); err != nil {
+		t.Fatal(err)
+	}
+	truncated := append(frame(10, "c.go"), frame(10, "d.go")[:5]...)
+	if err := s.handleDatagram(truncated); err != ErrDatagram {
+		t.Errorf("expect ErrDatagram, got %v", err)
+	}
+	for _, expect := range []string{"a.go", "b.go", "c.go"} {
+		if target := <-got; target != expect {
+			t.Errorf("expect %s, got %s", expect, target)
+		}
+	}
+	if len(got) != 0 {
+		t.Errorf("%d targets left", len(got))
+	}
+
+	started := make(chan error, 1)
+	go func() { started <- s.Start() }()
+	conn, err := net.Dial("udp", "127.0.0.1:8878")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// datagrams sent before the server listens are lost
+	var target string
+	for i := 0; i < 50 && len(target) == 0; i++ {
+		conn.Write(frame(10, "e.go"))
+		select {
+		case target = <-got:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	if target != "e.go" {
+		t.Errorf("expect e.go by datagram, got %q", target)
+	}
+
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-started; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUDPStopWaits(t *testing.T) {
+	lg.InitLogger(false, true, "")
+
+	entered, release := make(chan struct{}, 1), make(chan struct{})
+	router := map[uint8]RouteFunc{
+		10: func(b []byte, w io.Writer) {
+			entered <- struct{}{}
+			<-release
+		},
+	}
+	s := NewUDPServer(8884, router)
+	s.Hosts = []string{"127.0.0.1"}
+	go s.Start()
+
+	conn, err := net.Dial("udp", "127.0.0.1:8884")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for handling := false; !handling; {
+		conn.Write(frame(10, "a.go"))
+		select {
+		case <-entered:
+			handling = true
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Stop() }()
+	select {
+	case <-stopped:
+		t.Fatal("stop should wait for the datagram in hand")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Error(err)
+	}
+}
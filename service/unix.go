@@ -0,0 +1,41 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// DefaultSocketMode of the Unix socket, only the user of the service can
+// connect.
+const DefaultSocketMode os.FileMode = 0600
+
+// NewUnixServer to create a server on a Unix socket, with the same router
+// and framing as the TCP one. The socket file gets mode.
+func NewUnixServer(path string, mode os.FileMode, router map[uint8]RouteFunc) *TCPServer {
+	s := NewTCPServer(0, router)
+	s.socket, s.mode = path, mode
+	return s
+}
+
+// listenUnix on the socket of the server. A socket file left by a service
+// not running anymore is removed.
+func (s *TCPServer) listenUnix() (net.Listener, error) {
+	if fi, err := os.Lstat(s.socket); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if c, err := net.Dial("unix", s.socket); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("socket %s in use", s.socket)
+		}
+		os.Remove(s.socket)
+	}
+
+	ln, err := net.Listen("unix", s.socket)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(s.socket, s.mode); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
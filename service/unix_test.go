@@ -0,0 +1,68 @@
+package service
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/tracerun/tracerun/lg"
+)
+
+func TestUnixServer(t *testing.T) {
+	lg.InitLogger(false, true, "")
+	path := filepath.Join(t.TempDir(), "tracerun.sock")
+
+	// a socket left by a crashed service
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+
+	router := map[uint8]RouteFunc{
+		20: func(b []byte, w io.Writer) { writeMessage(w, 20, &Targets{Target: []string{"a.go"}}) },
+	}
+	s := NewUnixServer(path, 0600, router)
+	if err := s.listen(); err != nil {
+		t.Fatal(err)
+	}
+	go s.serve()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := fi.Mode().Perm(); mode != 0600 {
+		t.Errorf("expect mode 0600, got %o", mode)
+	}
+
+	// the socket in use is kept
+	if err := NewUnixServer(path, 0600, router).listen(); err == nil {
+		t.Error("a socket in use should not be taken")
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write(GenerateHeaderBuf(0, 20))
+	data, route, err := ReadOne(conn)
+	if err != nil || route != 20 {
+		t.Fatalf("wrong reply, route %d %v", route, err)
+	}
+	var got Targets
+	if err := proto.Unmarshal(data, &got); err != nil || len(got.Target) != 1 {
+		t.Errorf("wrong targets %v %v", got.Target, err)
+	}
+
+	s.Shutdown(time.Second)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket should be removed, %v", err)
+	}
+}
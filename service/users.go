@@ -0,0 +1,156 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Roles of the users file.
+const (
+	// RoleReader reads its namespace
+	RoleReader = "reader"
+	// RoleUser reads and adds actions in its namespace
+	RoleUser = "user"
+	// RoleAdmin reads and adds actions in the whole db and controls the
+	// service
+	RoleAdmin = "admin"
+)
+
+var (
+	rolePerms = map[string]int{
+		RoleReader: PermRead,
+		RoleUser:   PermWrite,
+		RoleAdmin:  PermAdmin,
+	}
+
+	userName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+	// users by token, guarded by authMu
+	users map[string]*account
+)
+
+// account of a token. The actions of a user are stored in its namespace, the
+// ones of an admin and of the token of the token file in the whole db.
+type account struct {
+	user string
+	perm int
+}
+
+// parseUsers to read the users, a line is "<user> <role> <token>". Empty
+// lines and lines starting with "#" are skipped.
+func parseUsers(r io.Reader) (map[string]*account, error) {
+	all := make(map[string]*account)
+	names := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expect \"<user> <role> <token>\"", n)
+		}
+		name, role, t := fields[0], fields[1], fields[2]
+		if !userName.MatchString(name) {
+			return nil, fmt.Errorf("line %d: user %q of letters, digits, '.', '_' or '-' only", n, name)
+		}
+		perm, ok := rolePerms[role]
+		if !ok {
+			return nil, fmt.Errorf("line %d: unknown role %q", n, role)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("line %d: user %s listed twice", n, name)
+		}
+		if _, ok := all[t]; ok {
+			return nil, fmt.Errorf("line %d: token of user %s already used", n, name)
+		}
+		names[name] = true
+		all[t] = &account{user: name, perm: perm}
+	}
+	return all, scanner.Err()
+}
+
+// readUsers to read a users file, none if it's missing.
+func readUsers(file string) (map[string]*account, error) {
+	b, err := readPrivate(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	all, err := parseUsers(strings.NewReader(string(b)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return all, nil
+}
+
+func setUsers(all map[string]*account) {
+	authMu.Lock()
+	users = all
+	authMu.Unlock()
+}
+
+// namespace of the targets of a user, empty without user.
+func namespace(user string) string {
+	if len(user) == 0 {
+		return ""
+	}
+	return "@" + user + ":"
+}
+
+// scopeOf to get the namespace a session reads and edits, empty for the
+// whole db.
+func scopeOf(w io.Writer) string {
+	s, ok := w.(*session)
+	if !ok || s.perm >= PermAdmin {
+		return ""
+	}
+	return namespace(s.user)
+}
+
+// unscope to get a target without the namespace, false if it's not in it.
+func unscope(prefix, target string) (string, bool) {
+	if !strings.HasPrefix(target, prefix) {
+		return "", false
+	}
+	return target[len(prefix):], true
+}
+
+// scopeTargets to get the targets in a namespace, without it.
+func scopeTargets(prefix string, targets []string) []string {
+	if len(prefix) == 0 {
+		return targets
+	}
+	var scoped []string
+	for _, target := range targets {
+		if t, ok := unscope(prefix, target); ok {
+			scoped = append(scoped, t)
+		}
+	}
+	return scoped
+}
+
+// tagAct to store an action in the namespace the session user reads, see
+// scopeOf, with the user and host in its metadata kept in the metadata file.
+func tagAct(w io.Writer, a *act) {
+	s, ok := w.(*session)
+	if !ok || len(s.user) == 0 {
+		return
+	}
+	a.target = scopeOf(w) + a.target
+	if a.meta == nil {
+		a.meta = make(map[string]string)
+	}
+	a.meta["user"] = s.user
+	if len(s.host) != 0 {
+		a.meta["host"] = s.host
+	}
+}
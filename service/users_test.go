@@ -0,0 +1,97 @@
+package service
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseUsers(t *testing.T) {
+	all, err := parseUsers(strings.NewReader(`
+# team
+alice user t1
+bob reader t2
+carol admin t3
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]*account{
+		"t1": {user: "alice", perm: PermWrite},
+		"t2": {user: "bob", perm: PermRead},
+		"t3": {user: "carol", perm: PermAdmin},
+	}
+	if !reflect.DeepEqual(all, expect) {
+		t.Errorf("wrong users %v", all)
+	}
+
+	for _, wrong := range []string{
+		"alice user",
+		"alice owner t1",
+		"al:ice user t1",
+		"alice user t1\nalice reader t2",
+		"alice user t1\nbob reader t1",
+	} {
+		if _, err := parseUsers(strings.NewReader(wrong)); err == nil {
+			t.Errorf("%q should fail", wrong)
+		}
+	}
+}
+
+func TestAuthenticateUsers(t *testing.T) {
+	setToken("secret")
+	setUsers(map[string]*account{"t1": {user: "alice", perm: PermWrite}})
+	defer setToken("")
+	defer setUsers(nil)
+
+	if acc, err := authenticate("t1"); err != nil || acc.user != "alice" || acc.perm != PermWrite {
+		t.Errorf("expect alice, got %v %v", acc, err)
+	}
+	if acc, err := authenticate("secret"); err != nil || len(acc.user) != 0 || acc.perm != PermAdmin {
+		t.Errorf("expect the token file, got %v %v", acc, err)
+	}
+	if _, err := authenticate("t2"); err != ErrWrongToken {
+		t.Errorf("expect wrong token, got %v", err)
+	}
+}
+
+func TestScope(t *testing.T) {
+	alice := &session{Writer: io.Discard, perm: PermWrite, user: "alice", host: "laptop"}
+	admin := &session{Writer: io.Discard, perm: PermAdmin, user: "carol"}
+
+	if prefix := scopeOf(alice); prefix != "@alice:" {
+		t.Errorf("expect alice namespace, got %q", prefix)
+	}
+	if prefix := scopeOf(admin); prefix != "" {
+		t.Errorf("expect the whole db for admin, got %q", prefix)
+	}
+	if prefix := scopeOf(io.Discard); prefix != "" {
+		t.Errorf("expect the whole db without session, got %q", prefix)
+	}
+
+	targets := []string{"a.go", "@alice:b.go", "@bob:c.go", "@alice:d/e.go"}
+	if got := scopeTargets("@alice:", targets); !reflect.DeepEqual(got, []string{"b.go", "d/e.go"}) {
+		t.Errorf("wrong targets of alice %v", got)
+	}
+	if got := scopeTargets("", targets); !reflect.DeepEqual(got, targets) {
+		t.Errorf("wrong targets of admin %v", got)
+	}
+
+	a := &act{target: "b.go"}
+	tagAct(alice, a)
+	if a.target != "@alice:b.go" || a.meta["user"] != "alice" || a.meta["host"] != "laptop" {
+		t.Errorf("wrong action of alice %s %v", a.target, a.meta)
+	}
+	// an admin user stores in the whole db it reads, tagged still
+	a = &act{target: "b.go"}
+	tagAct(admin, a)
+	if a.target != "b.go" || a.meta["user"] != "carol" {
+		t.Errorf("wrong action of carol %s %v", a.target, a.meta)
+	}
+	a = &act{target: "b.go"}
+	tagAct(newSession(io.Discard), a)
+	if a.target != "b.go" || a.meta != nil {
+		t.Errorf("an action without user should be kept, got %s %v", a.target, a.meta)
+	}
+}